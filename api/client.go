@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+)
+
+// APIClient groups the generated per-resource API services this package
+// exposes, wrapping each one so its methods take both a per-call ctx and the
+// long-lived Auth context (see authContext) instead of the single context
+// the generated client itself accepts.
+type APIClient struct {
+	ProjectsApi     ProjectsAPI
+	FeatureFlagsApi FeatureFlagsAPI
+	SegmentsApi     SegmentsAPI
+	AuditLogApi     AuditLogAPI
+}
+
+func newAPIClient(inner *ldapi.APIClient) *APIClient {
+	return &APIClient{
+		ProjectsApi:     ProjectsAPI{inner: inner.ProjectsApi},
+		FeatureFlagsApi: FeatureFlagsAPI{inner: inner.FeatureFlagsApi},
+		SegmentsApi:     SegmentsAPI{inner: inner.SegmentsApi},
+		AuditLogApi:     AuditLogAPI{inner: inner.AuditLogApi},
+	}
+}
+
+// ProjectsAPI wraps ldapi.ProjectsApiService, including the environment
+// endpoints it owns.
+type ProjectsAPI struct {
+	inner *ldapi.ProjectsApiService
+}
+
+func (p ProjectsAPI) GetProjects(ctx, auth context.Context) (ldapi.Projects, *http.Response, error) {
+	return p.inner.GetProjects(authContext(ctx, auth))
+}
+
+func (p ProjectsAPI) PostProject(ctx, auth context.Context, body ldapi.ProjectBody) (ldapi.Project, error) {
+	project, _, err := p.inner.PostProject(authContext(ctx, auth), body)
+	return project, err
+}
+
+func (p ProjectsAPI) GetProject(ctx, auth context.Context, key string) (ldapi.Project, *http.Response, error) {
+	return p.inner.GetProject(authContext(ctx, auth), key)
+}
+
+func (p ProjectsAPI) PatchProject(ctx, auth context.Context, key string, patch ldapi.PatchComment) (ldapi.Project, *http.Response, error) {
+	return p.inner.PatchProject(authContext(ctx, auth), key, patch)
+}
+
+func (p ProjectsAPI) DeleteProject(ctx, auth context.Context, key string) (*http.Response, error) {
+	return p.inner.DeleteProject(authContext(ctx, auth), key)
+}
+
+func (p ProjectsAPI) PostEnvironment(ctx, auth context.Context, projectKey string, env ldapi.EnvironmentBody) (ldapi.Environment, *http.Response, error) {
+	return p.inner.PostEnvironment(authContext(ctx, auth), projectKey, env)
+}
+
+func (p ProjectsAPI) GetEnvironment(ctx, auth context.Context, projectKey, envKey string) (ldapi.Environment, *http.Response, error) {
+	return p.inner.GetEnvironment(authContext(ctx, auth), projectKey, envKey)
+}
+
+func (p ProjectsAPI) PatchEnvironment(ctx, auth context.Context, projectKey, envKey string, patch ldapi.PatchComment) (ldapi.Environment, *http.Response, error) {
+	return p.inner.PatchEnvironment(authContext(ctx, auth), projectKey, envKey, patch)
+}
+
+func (p ProjectsAPI) DeleteEnvironment(ctx, auth context.Context, projectKey, envKey string) (*http.Response, error) {
+	return p.inner.DeleteEnvironment(authContext(ctx, auth), projectKey, envKey)
+}
+
+// FeatureFlagsAPI wraps ldapi.FeatureFlagsApiService.
+type FeatureFlagsAPI struct {
+	inner *ldapi.FeatureFlagsApiService
+}
+
+func (f FeatureFlagsAPI) GetFeatureFlags(ctx, auth context.Context, projectKey string, opts *ldapi.FeatureFlagsApiGetFeatureFlagsOpts) (ldapi.FeatureFlags, *http.Response, error) {
+	return f.inner.GetFeatureFlags(authContext(ctx, auth), projectKey, opts)
+}
+
+func (f FeatureFlagsAPI) PostFeatureFlag(ctx, auth context.Context, projectKey string, flag ldapi.FeatureFlagBody, opts *ldapi.FeatureFlagsApiPostFeatureFlagOpts) (ldapi.FeatureFlag, *http.Response, error) {
+	return f.inner.PostFeatureFlag(authContext(ctx, auth), projectKey, flag, opts)
+}
+
+func (f FeatureFlagsAPI) GetFeatureFlag(ctx, auth context.Context, projectKey, flagKey string, opts *ldapi.FeatureFlagsApiGetFeatureFlagOpts) (ldapi.FeatureFlag, *http.Response, error) {
+	return f.inner.GetFeatureFlag(authContext(ctx, auth), projectKey, flagKey, opts)
+}
+
+func (f FeatureFlagsAPI) PatchFeatureFlag(ctx, auth context.Context, projectKey, flagKey string, patch ldapi.PatchComment) (ldapi.FeatureFlag, *http.Response, error) {
+	return f.inner.PatchFeatureFlag(authContext(ctx, auth), projectKey, flagKey, patch)
+}
+
+func (f FeatureFlagsAPI) DeleteFeatureFlag(ctx, auth context.Context, projectKey, flagKey string) (*http.Response, error) {
+	return f.inner.DeleteFeatureFlag(authContext(ctx, auth), projectKey, flagKey)
+}
+
+// SegmentsAPI wraps ldapi.SegmentsApiService.
+type SegmentsAPI struct {
+	inner *ldapi.SegmentsApiService
+}
+
+func (s SegmentsAPI) GetSegments(ctx, auth context.Context, projectKey, envKey string) (ldapi.Segments, *http.Response, error) {
+	return s.inner.GetSegments(authContext(ctx, auth), projectKey, envKey)
+}
+
+func (s SegmentsAPI) PostSegment(ctx, auth context.Context, projectKey, envKey string, segment ldapi.SegmentBody) (ldapi.Segment, *http.Response, error) {
+	return s.inner.PostSegment(authContext(ctx, auth), projectKey, envKey, segment)
+}
+
+func (s SegmentsAPI) GetSegment(ctx, auth context.Context, projectKey, envKey, segmentKey string) (ldapi.Segment, *http.Response, error) {
+	return s.inner.GetSegment(authContext(ctx, auth), projectKey, envKey, segmentKey)
+}
+
+func (s SegmentsAPI) PatchSegment(ctx, auth context.Context, projectKey, envKey, segmentKey string, patch ldapi.PatchComment) (ldapi.Segment, *http.Response, error) {
+	return s.inner.PatchSegment(authContext(ctx, auth), projectKey, envKey, segmentKey, patch)
+}
+
+func (s SegmentsAPI) DeleteSegment(ctx, auth context.Context, projectKey, envKey, segmentKey string) (*http.Response, error) {
+	return s.inner.DeleteSegment(authContext(ctx, auth), projectKey, envKey, segmentKey)
+}
+
+// AuditLogAPI wraps ldapi.AuditLogApiService.
+type AuditLogAPI struct {
+	inner *ldapi.AuditLogApiService
+}
+
+func (a AuditLogAPI) GetAuditLogEntries(ctx, auth context.Context, opts *ldapi.AuditLogEntriesOptions) (ldapi.AuditLogEntries, *http.Response, error) {
+	return a.inner.GetAuditLogEntries(authContext(ctx, auth), opts)
+}
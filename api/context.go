@@ -0,0 +1,26 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// authContext merges a per-call ctx (the deadline/interrupt-cancellable
+// context built by cmd.apiContext) with auth (the long-lived context that
+// carries the API key via ldapi.ContextAPIKey). The generated client only
+// takes one context argument, and it needs both halves: auth's value, but
+// ctx's cancellation, so a `set timeout` or Ctrl-C still aborts the request
+// even though the value it authenticates with lives on a separate context
+// that's never itself cancelled.
+func authContext(ctx, auth context.Context) context.Context {
+	return mergedContext{Context: auth, cancelSrc: ctx}
+}
+
+type mergedContext struct {
+	context.Context
+	cancelSrc context.Context
+}
+
+func (m mergedContext) Deadline() (time.Time, bool) { return m.cancelSrc.Deadline() }
+func (m mergedContext) Done() <-chan struct{}       { return m.cancelSrc.Done() }
+func (m mergedContext) Err() error                  { return m.cancelSrc.Err() }
@@ -0,0 +1,598 @@
+// Package apply implements `ldc apply -f manifest.yaml`: a declarative,
+// manifest-driven bulk apply of projects, environments, flags and segments
+// against the current LaunchDarkly state. It is deliberately independent of
+// *ishell.Context so the diffing and patch-building logic can be unit tested
+// without a shell or a live API client; talking to the API itself goes
+// through the small apiActions interface below, so Apply can be exercised
+// against a fixture-backed fake too.
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/mattbaird/jsonpatch"
+	"gopkg.in/yaml.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/launchdarkly/ldc/api"
+)
+
+// Manifest is the desired state of a set of LaunchDarkly resources, as
+// parsed from the file passed to `apply -f`.
+type Manifest struct {
+	Projects []ProjectSpec `yaml:"projects" json:"projects"`
+}
+
+// ProjectSpec is the desired state of a single project, its environments and
+// its feature flags.
+type ProjectSpec struct {
+	Key          string            `yaml:"key" json:"key"`
+	Name         string            `yaml:"name" json:"name"`
+	Environments []EnvironmentSpec `yaml:"environments" json:"environments"`
+	Flags        []FlagSpec        `yaml:"flags" json:"flags"`
+}
+
+// EnvironmentSpec is the desired state of a single environment within a
+// project, including its segments.
+type EnvironmentSpec struct {
+	Key      string        `yaml:"key" json:"key"`
+	Name     string        `yaml:"name" json:"name"`
+	Color    string        `yaml:"color" json:"color"`
+	Segments []SegmentSpec `yaml:"segments" json:"segments"`
+}
+
+// FlagSpec is the desired state of a single feature flag within a project.
+type FlagSpec struct {
+	Key         string `yaml:"key" json:"key"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+}
+
+// SegmentSpec is the desired state of a single segment within an
+// environment.
+type SegmentSpec struct {
+	Key         string `yaml:"key" json:"key"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+}
+
+// Action is one step of a Plan.
+type Action struct {
+	Op          string // "create", "update" or "delete"
+	Resource    string // "project", "environment", "flag" or "segment"
+	Project     string
+	Environment string // set for "segment" actions, the environment that owns the segment
+	Key         string
+}
+
+// Plan is the ordered set of Actions required to bring LaunchDarkly state in
+// line with a Manifest. Actions are always emitted project-before-environment
+// and project-before-flag, so executing the plan in order never touches an
+// environment or flag before the project that owns it exists.
+type Plan struct {
+	Actions []Action
+}
+
+// CurrentState is the live LaunchDarkly state Diff compares a Manifest
+// against. Flags and segments are fetched separately from projects (the API
+// exposes them as their own collections, not nested in a project), so the
+// caller gathers all three before calling Diff.
+type CurrentState struct {
+	Projects []ldapi.Project
+	// Flags maps a project key to that project's flags.
+	Flags map[string][]ldapi.FeatureFlag
+	// Segments maps "project/environment" to that environment's segments.
+	Segments map[string][]ldapi.Segment
+}
+
+// LoadManifest reads and parses a manifest file, sniffing the format from its
+// extension unless the caller already knows it.
+func LoadManifest(path string, format string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = "yaml"
+		if strings.HasSuffix(path, ".json") {
+			format = "json"
+		}
+	}
+	var m Manifest
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, &m)
+	default:
+		err = yaml.Unmarshal(data, &m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Diff computes the Plan needed to reconcile current LaunchDarkly state with
+// the manifest. When prune is false, resources present in LaunchDarkly but
+// absent from the manifest are left alone instead of being deleted.
+func Diff(manifest *Manifest, current CurrentState, prune bool) *Plan {
+	plan := &Plan{}
+	seenProjects := map[string]bool{}
+
+	for _, spec := range manifest.Projects {
+		seenProjects[spec.Key] = true
+		existing := findProject(current.Projects, spec.Key)
+		if existing == nil {
+			plan.Actions = append(plan.Actions, Action{Op: "create", Resource: "project", Key: spec.Key})
+		} else if existing.Name != spec.Name {
+			plan.Actions = append(plan.Actions, Action{Op: "update", Resource: "project", Key: spec.Key})
+		}
+
+		var existingEnvs []ldapi.Environment
+		if existing != nil {
+			existingEnvs = existing.Environments
+		}
+		seenEnvs := map[string]bool{}
+		for _, envSpec := range spec.Environments {
+			seenEnvs[envSpec.Key] = true
+			if existingEnv := findEnvironment(existingEnvs, envSpec.Key); existingEnv == nil {
+				plan.Actions = append(plan.Actions, Action{Op: "create", Resource: "environment", Project: spec.Key, Key: envSpec.Key})
+			} else if existingEnv.Name != envSpec.Name || existingEnv.Color != envSpec.Color {
+				plan.Actions = append(plan.Actions, Action{Op: "update", Resource: "environment", Project: spec.Key, Key: envSpec.Key})
+			}
+
+			existingSegments := current.Segments[spec.Key+"/"+envSpec.Key]
+			seenSegments := map[string]bool{}
+			for _, segSpec := range envSpec.Segments {
+				seenSegments[segSpec.Key] = true
+				if existingSeg := findSegment(existingSegments, segSpec.Key); existingSeg == nil {
+					plan.Actions = append(plan.Actions, Action{Op: "create", Resource: "segment", Project: spec.Key, Environment: envSpec.Key, Key: segSpec.Key})
+				} else if existingSeg.Name != segSpec.Name || existingSeg.Description != segSpec.Description {
+					plan.Actions = append(plan.Actions, Action{Op: "update", Resource: "segment", Project: spec.Key, Environment: envSpec.Key, Key: segSpec.Key})
+				}
+			}
+			if prune {
+				for _, seg := range existingSegments {
+					if !seenSegments[seg.Key] {
+						plan.Actions = append(plan.Actions, Action{Op: "delete", Resource: "segment", Project: spec.Key, Environment: envSpec.Key, Key: seg.Key})
+					}
+				}
+			}
+		}
+		if prune {
+			for _, env := range existingEnvs {
+				if !seenEnvs[env.Key] {
+					plan.Actions = append(plan.Actions, Action{Op: "delete", Resource: "environment", Project: spec.Key, Key: env.Key})
+				}
+			}
+		}
+
+		existingFlags := current.Flags[spec.Key]
+		seenFlags := map[string]bool{}
+		for _, flagSpec := range spec.Flags {
+			seenFlags[flagSpec.Key] = true
+			if existingFlag := findFlag(existingFlags, flagSpec.Key); existingFlag == nil {
+				plan.Actions = append(plan.Actions, Action{Op: "create", Resource: "flag", Project: spec.Key, Key: flagSpec.Key})
+			} else if existingFlag.Name != flagSpec.Name || existingFlag.Description != flagSpec.Description {
+				plan.Actions = append(plan.Actions, Action{Op: "update", Resource: "flag", Project: spec.Key, Key: flagSpec.Key})
+			}
+		}
+		if prune {
+			for _, flag := range existingFlags {
+				if !seenFlags[flag.Key] {
+					plan.Actions = append(plan.Actions, Action{Op: "delete", Resource: "flag", Project: spec.Key, Key: flag.Key})
+				}
+			}
+		}
+	}
+
+	if prune {
+		for _, proj := range current.Projects {
+			if !seenProjects[proj.Key] {
+				plan.Actions = append(plan.Actions, Action{Op: "delete", Resource: "project", Key: proj.Key})
+			}
+		}
+	}
+
+	return plan
+}
+
+func findProject(projects []ldapi.Project, key string) *ldapi.Project {
+	for _, p := range projects {
+		if p.Key == key {
+			proj := p
+			return &proj
+		}
+	}
+	return nil
+}
+
+func findEnvironment(envs []ldapi.Environment, key string) *ldapi.Environment {
+	for _, e := range envs {
+		if e.Key == key {
+			env := e
+			return &env
+		}
+	}
+	return nil
+}
+
+func findFlag(flags []ldapi.FeatureFlag, key string) *ldapi.FeatureFlag {
+	for _, f := range flags {
+		if f.Key == key {
+			flag := f
+			return &flag
+		}
+	}
+	return nil
+}
+
+func findSegment(segments []ldapi.Segment, key string) *ldapi.Segment {
+	for _, s := range segments {
+		if s.Key == key {
+			seg := s
+			return &seg
+		}
+	}
+	return nil
+}
+
+func findProjectSpec(manifest *Manifest, key string) *ProjectSpec {
+	for _, spec := range manifest.Projects {
+		if spec.Key == key {
+			s := spec
+			return &s
+		}
+	}
+	return nil
+}
+
+func findEnvironmentSpec(spec *ProjectSpec, key string) *EnvironmentSpec {
+	for _, e := range spec.Environments {
+		if e.Key == key {
+			env := e
+			return &env
+		}
+	}
+	return nil
+}
+
+func findFlagSpec(spec *ProjectSpec, key string) *FlagSpec {
+	for _, f := range spec.Flags {
+		if f.Key == key {
+			flag := f
+			return &flag
+		}
+	}
+	return nil
+}
+
+func findSegmentSpec(env *EnvironmentSpec, key string) *SegmentSpec {
+	for _, s := range env.Segments {
+		if s.Key == key {
+			seg := s
+			return &seg
+		}
+	}
+	return nil
+}
+
+// apiActions is the slice of the LaunchDarkly API that Apply needs, factored
+// out so it can be exercised against an in-memory fake loaded from fixtures
+// instead of a live api.Client.
+type apiActions interface {
+	createProject(ctx context.Context, key, name string) error
+	getProject(ctx context.Context, key string) (ldapi.Project, error)
+	patchProject(ctx context.Context, key string, patch ldapi.PatchComment) error
+	deleteProject(ctx context.Context, key string) error
+
+	createEnvironment(ctx context.Context, projectKey string, env ldapi.EnvironmentBody) error
+	getEnvironment(ctx context.Context, projectKey, envKey string) (ldapi.Environment, error)
+	patchEnvironment(ctx context.Context, projectKey, envKey string, patch ldapi.PatchComment) error
+	deleteEnvironment(ctx context.Context, projectKey, envKey string) error
+
+	createFlag(ctx context.Context, projectKey string, flag ldapi.FeatureFlagBody) error
+	getFlag(ctx context.Context, projectKey, flagKey string) (ldapi.FeatureFlag, error)
+	patchFlag(ctx context.Context, projectKey, flagKey string, patch ldapi.PatchComment) error
+	deleteFlag(ctx context.Context, projectKey, flagKey string) error
+
+	createSegment(ctx context.Context, projectKey, envKey string, segment ldapi.SegmentBody) error
+	getSegment(ctx context.Context, projectKey, envKey, segmentKey string) (ldapi.Segment, error)
+	patchSegment(ctx context.Context, projectKey, envKey, segmentKey string, patch ldapi.PatchComment) error
+	deleteSegment(ctx context.Context, projectKey, envKey, segmentKey string) error
+}
+
+// liveAPI is apiActions backed by the real api.Client; it's what Apply uses
+// in production.
+type liveAPI struct{}
+
+func (liveAPI) createProject(ctx context.Context, key, name string) error {
+	_, err := api.Client.ProjectsApi.PostProject(ctx, api.Auth, ldapi.ProjectBody{Key: key, Name: name})
+	return err
+}
+
+func (liveAPI) getProject(ctx context.Context, key string) (ldapi.Project, error) {
+	project, _, err := api.Client.ProjectsApi.GetProject(ctx, api.Auth, key)
+	return project, err
+}
+
+func (liveAPI) patchProject(ctx context.Context, key string, patch ldapi.PatchComment) error {
+	_, _, err := api.Client.ProjectsApi.PatchProject(ctx, api.Auth, key, patch)
+	return err
+}
+
+func (liveAPI) deleteProject(ctx context.Context, key string) error {
+	_, err := api.Client.ProjectsApi.DeleteProject(ctx, api.Auth, key)
+	return err
+}
+
+func (liveAPI) createEnvironment(ctx context.Context, projectKey string, env ldapi.EnvironmentBody) error {
+	_, _, err := api.Client.ProjectsApi.PostEnvironment(ctx, api.Auth, projectKey, env)
+	return err
+}
+
+func (liveAPI) getEnvironment(ctx context.Context, projectKey, envKey string) (ldapi.Environment, error) {
+	env, _, err := api.Client.ProjectsApi.GetEnvironment(ctx, api.Auth, projectKey, envKey)
+	return env, err
+}
+
+func (liveAPI) patchEnvironment(ctx context.Context, projectKey, envKey string, patch ldapi.PatchComment) error {
+	_, _, err := api.Client.ProjectsApi.PatchEnvironment(ctx, api.Auth, projectKey, envKey, patch)
+	return err
+}
+
+func (liveAPI) deleteEnvironment(ctx context.Context, projectKey, envKey string) error {
+	_, err := api.Client.ProjectsApi.DeleteEnvironment(ctx, api.Auth, projectKey, envKey)
+	return err
+}
+
+func (liveAPI) createFlag(ctx context.Context, projectKey string, flag ldapi.FeatureFlagBody) error {
+	_, _, err := api.Client.FeatureFlagsApi.PostFeatureFlag(ctx, api.Auth, projectKey, flag, nil)
+	return err
+}
+
+func (liveAPI) getFlag(ctx context.Context, projectKey, flagKey string) (ldapi.FeatureFlag, error) {
+	flag, _, err := api.Client.FeatureFlagsApi.GetFeatureFlag(ctx, api.Auth, projectKey, flagKey, nil)
+	return flag, err
+}
+
+func (liveAPI) patchFlag(ctx context.Context, projectKey, flagKey string, patch ldapi.PatchComment) error {
+	_, _, err := api.Client.FeatureFlagsApi.PatchFeatureFlag(ctx, api.Auth, projectKey, flagKey, patch)
+	return err
+}
+
+func (liveAPI) deleteFlag(ctx context.Context, projectKey, flagKey string) error {
+	_, err := api.Client.FeatureFlagsApi.DeleteFeatureFlag(ctx, api.Auth, projectKey, flagKey)
+	return err
+}
+
+func (liveAPI) createSegment(ctx context.Context, projectKey, envKey string, segment ldapi.SegmentBody) error {
+	_, _, err := api.Client.SegmentsApi.PostSegment(ctx, api.Auth, projectKey, envKey, segment)
+	return err
+}
+
+func (liveAPI) getSegment(ctx context.Context, projectKey, envKey, segmentKey string) (ldapi.Segment, error) {
+	segment, _, err := api.Client.SegmentsApi.GetSegment(ctx, api.Auth, projectKey, envKey, segmentKey)
+	return segment, err
+}
+
+func (liveAPI) patchSegment(ctx context.Context, projectKey, envKey, segmentKey string, patch ldapi.PatchComment) error {
+	_, _, err := api.Client.SegmentsApi.PatchSegment(ctx, api.Auth, projectKey, envKey, segmentKey, patch)
+	return err
+}
+
+func (liveAPI) deleteSegment(ctx context.Context, projectKey, envKey, segmentKey string) error {
+	_, err := api.Client.SegmentsApi.DeleteSegment(ctx, api.Auth, projectKey, envKey, segmentKey)
+	return err
+}
+
+// Apply executes a Plan against the LaunchDarkly API, routing every update
+// through the same jsonpatch.CreatePatch path editFile uses. ctx governs the
+// whole plan: every action runs under that one ctx, so its deadline covers
+// all of them together.
+//
+// onProjectsChanged, if non-nil, is called after any action that creates or
+// deletes a project, so callers with their own project-key cache (e.g.
+// cmd.projectKeyCache) can invalidate it without Apply needing to know it
+// exists.
+func Apply(ctx context.Context, plan *Plan, manifest *Manifest, onProjectsChanged func()) error {
+	return applyWith(ctx, liveAPI{}, plan, manifest, onProjectsChanged)
+}
+
+func applyWith(ctx context.Context, client apiActions, plan *Plan, manifest *Manifest, onProjectsChanged func()) error {
+	for _, action := range plan.Actions {
+		var err error
+		switch {
+		case action.Resource == "project" && action.Op == "create":
+			if err = createProjectAction(ctx, client, action.Key, manifest); err == nil && onProjectsChanged != nil {
+				onProjectsChanged()
+			}
+		case action.Resource == "project" && action.Op == "update":
+			err = patchProjectAction(ctx, client, action.Key, manifest)
+		case action.Resource == "project" && action.Op == "delete":
+			if err = client.deleteProject(ctx, action.Key); err == nil && onProjectsChanged != nil {
+				onProjectsChanged()
+			}
+		case action.Resource == "environment" && action.Op == "create":
+			err = createEnvironmentAction(ctx, client, action.Project, action.Key, manifest)
+		case action.Resource == "environment" && action.Op == "update":
+			err = patchEnvironmentAction(ctx, client, action.Project, action.Key, manifest)
+		case action.Resource == "environment" && action.Op == "delete":
+			err = client.deleteEnvironment(ctx, action.Project, action.Key)
+		case action.Resource == "flag" && action.Op == "create":
+			err = createFlagAction(ctx, client, action.Project, action.Key, manifest)
+		case action.Resource == "flag" && action.Op == "update":
+			err = patchFlagAction(ctx, client, action.Project, action.Key, manifest)
+		case action.Resource == "flag" && action.Op == "delete":
+			err = client.deleteFlag(ctx, action.Project, action.Key)
+		case action.Resource == "segment" && action.Op == "create":
+			err = createSegmentAction(ctx, client, action.Project, action.Environment, action.Key, manifest)
+		case action.Resource == "segment" && action.Op == "update":
+			err = patchSegmentAction(ctx, client, action.Project, action.Environment, action.Key, manifest)
+		case action.Resource == "segment" && action.Op == "delete":
+			err = client.deleteSegment(ctx, action.Project, action.Environment, action.Key)
+		default:
+			err = errors.New("apply: unsupported action " + action.Op + " " + action.Resource)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createProjectAction(ctx context.Context, client apiActions, key string, manifest *Manifest) error {
+	spec := findProjectSpec(manifest, key)
+	return client.createProject(ctx, spec.Key, spec.Name)
+}
+
+func patchProjectAction(ctx context.Context, client apiActions, key string, manifest *Manifest) error {
+	spec := findProjectSpec(manifest, key)
+	current, err := client.getProject(ctx, key)
+	if err != nil {
+		return err
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	desired := current
+	desired.Name = spec.Name
+	updated, err := json.Marshal(desired)
+	if err != nil {
+		return err
+	}
+	return patchComment(original, updated, func(patch ldapi.PatchComment) error {
+		return client.patchProject(ctx, key, patch)
+	})
+}
+
+func createEnvironmentAction(ctx context.Context, client apiActions, projectKey, envKey string, manifest *Manifest) error {
+	spec := findProjectSpec(manifest, projectKey)
+	envSpec := findEnvironmentSpec(spec, envKey)
+	if envSpec == nil {
+		envSpec = &EnvironmentSpec{Key: envKey}
+	}
+	return client.createEnvironment(ctx, projectKey, ldapi.EnvironmentBody{
+		Key:   envSpec.Key,
+		Name:  envSpec.Name,
+		Color: envSpec.Color,
+	})
+}
+
+func patchEnvironmentAction(ctx context.Context, client apiActions, projectKey, envKey string, manifest *Manifest) error {
+	spec := findProjectSpec(manifest, projectKey)
+	envSpec := findEnvironmentSpec(spec, envKey)
+	current, err := client.getEnvironment(ctx, projectKey, envKey)
+	if err != nil {
+		return err
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	desired := current
+	desired.Name = envSpec.Name
+	desired.Color = envSpec.Color
+	updated, err := json.Marshal(desired)
+	if err != nil {
+		return err
+	}
+	return patchComment(original, updated, func(patch ldapi.PatchComment) error {
+		return client.patchEnvironment(ctx, projectKey, envKey, patch)
+	})
+}
+
+func createFlagAction(ctx context.Context, client apiActions, projectKey, flagKey string, manifest *Manifest) error {
+	spec := findProjectSpec(manifest, projectKey)
+	flagSpec := findFlagSpec(spec, flagKey)
+	if flagSpec == nil {
+		flagSpec = &FlagSpec{Key: flagKey}
+	}
+	return client.createFlag(ctx, projectKey, ldapi.FeatureFlagBody{
+		Key:         flagSpec.Key,
+		Name:        flagSpec.Name,
+		Description: flagSpec.Description,
+	})
+}
+
+func patchFlagAction(ctx context.Context, client apiActions, projectKey, flagKey string, manifest *Manifest) error {
+	spec := findProjectSpec(manifest, projectKey)
+	flagSpec := findFlagSpec(spec, flagKey)
+	current, err := client.getFlag(ctx, projectKey, flagKey)
+	if err != nil {
+		return err
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	desired := current
+	desired.Name = flagSpec.Name
+	desired.Description = flagSpec.Description
+	updated, err := json.Marshal(desired)
+	if err != nil {
+		return err
+	}
+	return patchComment(original, updated, func(patch ldapi.PatchComment) error {
+		return client.patchFlag(ctx, projectKey, flagKey, patch)
+	})
+}
+
+func createSegmentAction(ctx context.Context, client apiActions, projectKey, envKey, segmentKey string, manifest *Manifest) error {
+	spec := findProjectSpec(manifest, projectKey)
+	envSpec := findEnvironmentSpec(spec, envKey)
+	segSpec := findSegmentSpec(envSpec, segmentKey)
+	if segSpec == nil {
+		segSpec = &SegmentSpec{Key: segmentKey}
+	}
+	return client.createSegment(ctx, projectKey, envKey, ldapi.SegmentBody{
+		Key:         segSpec.Key,
+		Name:        segSpec.Name,
+		Description: segSpec.Description,
+	})
+}
+
+func patchSegmentAction(ctx context.Context, client apiActions, projectKey, envKey, segmentKey string, manifest *Manifest) error {
+	spec := findProjectSpec(manifest, projectKey)
+	envSpec := findEnvironmentSpec(spec, envKey)
+	segSpec := findSegmentSpec(envSpec, segmentKey)
+	current, err := client.getSegment(ctx, projectKey, envKey, segmentKey)
+	if err != nil {
+		return err
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	desired := current
+	desired.Name = segSpec.Name
+	desired.Description = segSpec.Description
+	updated, err := json.Marshal(desired)
+	if err != nil {
+		return err
+	}
+	return patchComment(original, updated, func(patch ldapi.PatchComment) error {
+		return client.patchSegment(ctx, projectKey, envKey, segmentKey, patch)
+	})
+}
+
+func patchComment(original, updated []byte, apply func(ldapi.PatchComment) error) error {
+	ops, err := jsonpatch.CreatePatch(original, updated)
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	var patch ldapi.PatchComment
+	patch.Comment = "ldc apply"
+	for _, op := range ops {
+		op := op // avoid aliasing the range variable across iterations
+		patch.Patch = append(patch.Patch, ldapi.PatchOperation{Op: op.Operation, Path: op.Path, Value: &op.Value})
+	}
+	return apply(patch)
+}
@@ -0,0 +1,465 @@
+package apply
+
+import (
+	"context"
+	"testing"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+)
+
+func TestDiffCreatesMissingProject(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{Key: "new-proj", Name: "New Proj"}}}
+
+	plan := Diff(manifest, CurrentState{}, false)
+
+	if len(plan.Actions) != 1 {
+		t.Fatalf("expected 1 action, got %d: %+v", len(plan.Actions), plan.Actions)
+	}
+	if a := plan.Actions[0]; a.Op != "create" || a.Resource != "project" || a.Key != "new-proj" {
+		t.Fatalf("unexpected action: %+v", a)
+	}
+}
+
+func TestDiffUpdatesChangedProject(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{Key: "existing", Name: "New Name"}}}
+	current := CurrentState{Projects: []ldapi.Project{{Key: "existing", Name: "Old Name"}}}
+
+	plan := Diff(manifest, current, false)
+
+	if len(plan.Actions) != 1 || plan.Actions[0].Op != "update" {
+		t.Fatalf("expected a single update action, got %+v", plan.Actions)
+	}
+}
+
+func TestDiffLeavesUnmanagedProjectsWithoutPrune(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{Key: "managed", Name: "Managed"}}}
+	current := CurrentState{Projects: []ldapi.Project{{Key: "managed", Name: "Managed"}, {Key: "unmanaged", Name: "Unmanaged"}}}
+
+	plan := Diff(manifest, current, false)
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("expected no actions, got %+v", plan.Actions)
+	}
+}
+
+func TestDiffPrunesUnmanagedProjects(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{Key: "managed", Name: "Managed"}}}
+	current := CurrentState{Projects: []ldapi.Project{{Key: "managed", Name: "Managed"}, {Key: "unmanaged", Name: "Unmanaged"}}}
+
+	plan := Diff(manifest, current, true)
+
+	if len(plan.Actions) != 1 || plan.Actions[0].Op != "delete" || plan.Actions[0].Key != "unmanaged" {
+		t.Fatalf("expected a single delete action for 'unmanaged', got %+v", plan.Actions)
+	}
+}
+
+func TestDiffOrdersProjectBeforeItsEnvironments(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{
+		Key:          "new-proj",
+		Name:         "New Proj",
+		Environments: []EnvironmentSpec{{Key: "production", Name: "Production"}},
+	}}}
+
+	plan := Diff(manifest, CurrentState{}, false)
+
+	if len(plan.Actions) != 2 {
+		t.Fatalf("expected 2 actions, got %+v", plan.Actions)
+	}
+	if plan.Actions[0].Resource != "project" || plan.Actions[1].Resource != "environment" {
+		t.Fatalf("expected project action before environment action, got %+v", plan.Actions)
+	}
+}
+
+func TestDiffCreatesMissingFlagAndSegment(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{
+		Key:  "existing",
+		Name: "Existing",
+		Environments: []EnvironmentSpec{{
+			Key:      "production",
+			Segments: []SegmentSpec{{Key: "beta-users", Name: "Beta users"}},
+		}},
+		Flags: []FlagSpec{{Key: "new-flag", Name: "New Flag"}},
+	}}}
+	current := CurrentState{
+		Projects: []ldapi.Project{{Key: "existing", Name: "Existing", Environments: []ldapi.Environment{{Key: "production"}}}},
+	}
+
+	plan := Diff(manifest, current, false)
+
+	if len(plan.Actions) != 2 {
+		t.Fatalf("expected a flag create and a segment create, got %+v", plan.Actions)
+	}
+	if plan.Actions[0].Resource != "segment" || plan.Actions[0].Environment != "production" || plan.Actions[0].Key != "beta-users" {
+		t.Fatalf("unexpected segment action: %+v", plan.Actions[0])
+	}
+	if plan.Actions[1].Resource != "flag" || plan.Actions[1].Key != "new-flag" {
+		t.Fatalf("unexpected flag action: %+v", plan.Actions[1])
+	}
+}
+
+func TestDiffUpdatesChangedEnvironment(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{
+		Key:          "existing",
+		Name:         "Existing",
+		Environments: []EnvironmentSpec{{Key: "production", Name: "Production", Color: "ff0000"}},
+	}}}
+	current := CurrentState{
+		Projects: []ldapi.Project{{Key: "existing", Name: "Existing", Environments: []ldapi.Environment{{Key: "production", Name: "Production", Color: "00ff00"}}}},
+	}
+
+	plan := Diff(manifest, current, false)
+
+	if len(plan.Actions) != 1 || plan.Actions[0].Op != "update" || plan.Actions[0].Resource != "environment" {
+		t.Fatalf("expected a single environment update action, got %+v", plan.Actions)
+	}
+}
+
+func TestDiffPrunesUnmanagedEnvironment(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{
+		Key:          "existing",
+		Name:         "Existing",
+		Environments: []EnvironmentSpec{{Key: "production", Name: "Production"}},
+	}}}
+	current := CurrentState{
+		Projects: []ldapi.Project{{Key: "existing", Name: "Existing", Environments: []ldapi.Environment{
+			{Key: "production", Name: "Production"},
+			{Key: "staging", Name: "Staging"},
+		}}},
+	}
+
+	plan := Diff(manifest, current, true)
+
+	if len(plan.Actions) != 1 || plan.Actions[0].Op != "delete" || plan.Actions[0].Resource != "environment" || plan.Actions[0].Key != "staging" {
+		t.Fatalf("expected a single environment delete action for 'staging', got %+v", plan.Actions)
+	}
+}
+
+func TestDiffPrunesUnmanagedFlagsAndSegments(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{
+		Key:          "existing",
+		Name:         "Existing",
+		Environments: []EnvironmentSpec{{Key: "production"}},
+	}}}
+	current := CurrentState{
+		Projects: []ldapi.Project{{Key: "existing", Name: "Existing", Environments: []ldapi.Environment{{Key: "production"}}}},
+		Flags:    map[string][]ldapi.FeatureFlag{"existing": {{Key: "unmanaged-flag", Name: "Unmanaged"}}},
+		Segments: map[string][]ldapi.Segment{"existing/production": {{Key: "unmanaged-segment", Name: "Unmanaged"}}},
+	}
+
+	plan := Diff(manifest, current, true)
+
+	if len(plan.Actions) != 2 {
+		t.Fatalf("expected prune actions for the flag and segment, got %+v", plan.Actions)
+	}
+	if plan.Actions[0].Resource != "segment" || plan.Actions[0].Op != "delete" || plan.Actions[0].Key != "unmanaged-segment" {
+		t.Fatalf("unexpected action: %+v", plan.Actions[0])
+	}
+	if plan.Actions[1].Resource != "flag" || plan.Actions[1].Op != "delete" || plan.Actions[1].Key != "unmanaged-flag" {
+		t.Fatalf("unexpected action: %+v", plan.Actions[1])
+	}
+}
+
+// fakeAPI is an apiActions backed by an in-memory fixture instead of a live
+// api.Client, so Apply's dispatch and patch-building can be integration
+// tested without a real LaunchDarkly account.
+type fakeAPI struct {
+	projects map[string]ldapi.Project
+	flags    map[string]ldapi.FeatureFlag // keyed by "project/flag"
+	segments map[string]ldapi.Segment     // keyed by "project/env/segment"
+
+	deletedProjects []string
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		projects: map[string]ldapi.Project{},
+		flags:    map[string]ldapi.FeatureFlag{},
+		segments: map[string]ldapi.Segment{},
+	}
+}
+
+func (f *fakeAPI) createProject(ctx context.Context, key, name string) error {
+	f.projects[key] = ldapi.Project{Key: key, Name: name}
+	return nil
+}
+
+func (f *fakeAPI) getProject(ctx context.Context, key string) (ldapi.Project, error) {
+	return f.projects[key], nil
+}
+
+func (f *fakeAPI) patchProject(ctx context.Context, key string, patch ldapi.PatchComment) error {
+	project := f.projects[key]
+	for _, op := range patch.Patch {
+		if op.Path == "/name" {
+			project.Name = (*op.Value).(string)
+		}
+	}
+	f.projects[key] = project
+	return nil
+}
+
+func (f *fakeAPI) deleteProject(ctx context.Context, key string) error {
+	delete(f.projects, key)
+	f.deletedProjects = append(f.deletedProjects, key)
+	return nil
+}
+
+func (f *fakeAPI) createEnvironment(ctx context.Context, projectKey string, env ldapi.EnvironmentBody) error {
+	project := f.projects[projectKey]
+	project.Environments = append(project.Environments, ldapi.Environment{Key: env.Key, Name: env.Name, Color: env.Color})
+	f.projects[projectKey] = project
+	return nil
+}
+
+func (f *fakeAPI) getEnvironment(ctx context.Context, projectKey, envKey string) (ldapi.Environment, error) {
+	for _, e := range f.projects[projectKey].Environments {
+		if e.Key == envKey {
+			return e, nil
+		}
+	}
+	return ldapi.Environment{}, nil
+}
+
+func (f *fakeAPI) patchEnvironment(ctx context.Context, projectKey, envKey string, patch ldapi.PatchComment) error {
+	project := f.projects[projectKey]
+	for i, e := range project.Environments {
+		if e.Key != envKey {
+			continue
+		}
+		for _, op := range patch.Patch {
+			switch op.Path {
+			case "/name":
+				e.Name = (*op.Value).(string)
+			case "/color":
+				e.Color = (*op.Value).(string)
+			}
+		}
+		project.Environments[i] = e
+	}
+	f.projects[projectKey] = project
+	return nil
+}
+
+func (f *fakeAPI) deleteEnvironment(ctx context.Context, projectKey, envKey string) error {
+	project := f.projects[projectKey]
+	kept := project.Environments[:0]
+	for _, e := range project.Environments {
+		if e.Key != envKey {
+			kept = append(kept, e)
+		}
+	}
+	project.Environments = kept
+	f.projects[projectKey] = project
+	return nil
+}
+
+func (f *fakeAPI) createFlag(ctx context.Context, projectKey string, flag ldapi.FeatureFlagBody) error {
+	f.flags[projectKey+"/"+flag.Key] = ldapi.FeatureFlag{Key: flag.Key, Name: flag.Name, Description: flag.Description}
+	return nil
+}
+
+func (f *fakeAPI) getFlag(ctx context.Context, projectKey, flagKey string) (ldapi.FeatureFlag, error) {
+	return f.flags[projectKey+"/"+flagKey], nil
+}
+
+func (f *fakeAPI) patchFlag(ctx context.Context, projectKey, flagKey string, patch ldapi.PatchComment) error {
+	flag := f.flags[projectKey+"/"+flagKey]
+	for _, op := range patch.Patch {
+		switch op.Path {
+		case "/name":
+			flag.Name = (*op.Value).(string)
+		case "/description":
+			flag.Description = (*op.Value).(string)
+		}
+	}
+	f.flags[projectKey+"/"+flagKey] = flag
+	return nil
+}
+
+func (f *fakeAPI) deleteFlag(ctx context.Context, projectKey, flagKey string) error {
+	delete(f.flags, projectKey+"/"+flagKey)
+	return nil
+}
+
+func (f *fakeAPI) createSegment(ctx context.Context, projectKey, envKey string, segment ldapi.SegmentBody) error {
+	f.segments[projectKey+"/"+envKey+"/"+segment.Key] = ldapi.Segment{Key: segment.Key, Name: segment.Name, Description: segment.Description}
+	return nil
+}
+
+func (f *fakeAPI) getSegment(ctx context.Context, projectKey, envKey, segmentKey string) (ldapi.Segment, error) {
+	return f.segments[projectKey+"/"+envKey+"/"+segmentKey], nil
+}
+
+func (f *fakeAPI) patchSegment(ctx context.Context, projectKey, envKey, segmentKey string, patch ldapi.PatchComment) error {
+	key := projectKey + "/" + envKey + "/" + segmentKey
+	segment := f.segments[key]
+	for _, op := range patch.Patch {
+		switch op.Path {
+		case "/name":
+			segment.Name = (*op.Value).(string)
+		case "/description":
+			segment.Description = (*op.Value).(string)
+		}
+	}
+	f.segments[key] = segment
+	return nil
+}
+
+func (f *fakeAPI) deleteSegment(ctx context.Context, projectKey, envKey, segmentKey string) error {
+	delete(f.segments, projectKey+"/"+envKey+"/"+segmentKey)
+	return nil
+}
+
+func TestApplyCreatesProjectEnvironmentFlagAndSegment(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{
+		Key:  "new-proj",
+		Name: "New Proj",
+		Environments: []EnvironmentSpec{{
+			Key:      "production",
+			Name:     "Production",
+			Segments: []SegmentSpec{{Key: "beta-users", Name: "Beta users"}},
+		}},
+		Flags: []FlagSpec{{Key: "new-flag", Name: "New Flag"}},
+	}}}
+	plan := Diff(manifest, CurrentState{}, false)
+
+	client := newFakeAPI()
+	invalidated := 0
+	if err := applyWith(context.Background(), client, plan, manifest, func() { invalidated++ }); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	if _, ok := client.projects["new-proj"]; !ok {
+		t.Fatalf("expected project to be created, got %+v", client.projects)
+	}
+	if invalidated != 1 {
+		t.Fatalf("expected the project cache to be invalidated once for the project create, got %d", invalidated)
+	}
+	if _, ok := client.flags["new-proj/new-flag"]; !ok {
+		t.Fatalf("expected flag to be created, got %+v", client.flags)
+	}
+	if _, ok := client.segments["new-proj/production/beta-users"]; !ok {
+		t.Fatalf("expected segment to be created, got %+v", client.segments)
+	}
+}
+
+func TestApplyInvalidatesOnProjectDelete(t *testing.T) {
+	manifest := &Manifest{}
+	plan := &Plan{Actions: []Action{{Op: "delete", Resource: "project", Key: "gone"}}}
+
+	client := newFakeAPI()
+	client.projects["gone"] = ldapi.Project{Key: "gone"}
+	invalidated := 0
+	if err := applyWith(context.Background(), client, plan, manifest, func() { invalidated++ }); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	if invalidated != 1 {
+		t.Fatalf("expected invalidation on project delete, got %d", invalidated)
+	}
+	if len(client.deletedProjects) != 1 || client.deletedProjects[0] != "gone" {
+		t.Fatalf("expected 'gone' to be deleted, got %+v", client.deletedProjects)
+	}
+}
+
+func TestApplyDoesNotInvalidateOnFlagOnlyChange(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{Key: "existing", Flags: []FlagSpec{{Key: "f", Name: "F"}}}}}
+	plan := &Plan{Actions: []Action{{Op: "create", Resource: "flag", Project: "existing", Key: "f"}}}
+
+	client := newFakeAPI()
+	invalidated := 0
+	if err := applyWith(context.Background(), client, plan, manifest, func() { invalidated++ }); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	if invalidated != 0 {
+		t.Fatalf("expected no project-cache invalidation for a flag-only change, got %d", invalidated)
+	}
+}
+
+// TestPatchCommentSetsEachOpsOwnValue guards against aliasing the jsonpatch
+// range variable: a patch that changes more than one field must not have
+// every entry read back the last op's value.
+func TestPatchCommentSetsEachOpsOwnValue(t *testing.T) {
+	original := []byte(`{"key":"f","name":"Old Name","description":"Old Description"}`)
+	updated := []byte(`{"key":"f","name":"New Name","description":"New Description"}`)
+
+	var applied ldapi.PatchComment
+	if err := patchComment(original, updated, func(patch ldapi.PatchComment) error {
+		applied = patch
+		return nil
+	}); err != nil {
+		t.Fatalf("patchComment: %v", err)
+	}
+
+	if len(applied.Patch) != 2 {
+		t.Fatalf("expected 2 patch ops, got %+v", applied.Patch)
+	}
+	values := map[string]interface{}{}
+	for _, op := range applied.Patch {
+		values[op.Path] = *op.Value
+	}
+	if values["/name"] != "New Name" {
+		t.Fatalf("expected /name to read back its own value, got %+v", values)
+	}
+	if values["/description"] != "New Description" {
+		t.Fatalf("expected /description to read back its own value, got %+v", values)
+	}
+}
+
+func TestApplyEnvironmentPatchUpdatesNameAndColor(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{
+		Key:          "existing",
+		Environments: []EnvironmentSpec{{Key: "production", Name: "New Name", Color: "ff0000"}},
+	}}}
+	plan := &Plan{Actions: []Action{{Op: "update", Resource: "environment", Project: "existing", Key: "production"}}}
+
+	client := newFakeAPI()
+	client.projects["existing"] = ldapi.Project{Key: "existing", Environments: []ldapi.Environment{{Key: "production", Name: "Old Name", Color: "00ff00"}}}
+
+	if err := applyWith(context.Background(), client, plan, manifest, nil); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	got := client.projects["existing"].Environments[0]
+	if got.Name != "New Name" || got.Color != "ff0000" {
+		t.Fatalf("expected the environment to be patched, got %+v", got)
+	}
+}
+
+func TestApplyEnvironmentDeleteRemovesIt(t *testing.T) {
+	manifest := &Manifest{}
+	plan := &Plan{Actions: []Action{{Op: "delete", Resource: "environment", Project: "existing", Key: "staging"}}}
+
+	client := newFakeAPI()
+	client.projects["existing"] = ldapi.Project{Key: "existing", Environments: []ldapi.Environment{{Key: "production"}, {Key: "staging"}}}
+
+	if err := applyWith(context.Background(), client, plan, manifest, nil); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	envs := client.projects["existing"].Environments
+	if len(envs) != 1 || envs[0].Key != "production" {
+		t.Fatalf("expected 'staging' to be removed, got %+v", envs)
+	}
+}
+
+func TestApplyFlagPatchAppliesEachFieldIndependently(t *testing.T) {
+	manifest := &Manifest{Projects: []ProjectSpec{{
+		Key:   "existing",
+		Flags: []FlagSpec{{Key: "f", Name: "New Name", Description: "New Description"}},
+	}}}
+	plan := &Plan{Actions: []Action{{Op: "update", Resource: "flag", Project: "existing", Key: "f"}}}
+
+	client := newFakeAPI()
+	client.flags["existing/f"] = ldapi.FeatureFlag{Key: "f", Name: "Old Name", Description: "Old Description"}
+
+	if err := applyWith(context.Background(), client, plan, manifest, nil); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	got := client.flags["existing/f"]
+	if got.Name != "New Name" || got.Description != "New Description" {
+		t.Fatalf("expected both fields to be patched independently, got %+v", got)
+	}
+}
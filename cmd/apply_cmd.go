@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/olekukonko/tablewriter"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/api-client-go"
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/apply"
+)
+
+func AddApplyCommands(shell *ishell.Shell) {
+	shell.AddCmd(&ishell.Cmd{
+		Name: "apply",
+		Help: "apply a manifest: apply -f manifest.yaml [--dry-run] [--prune]",
+		Func: runApply,
+	})
+}
+
+// runApply executes a manifest's plan. It passes projectKeyCache.Invalidate
+// into apply.Apply so a project created or deleted by `apply` shows up in
+// completion immediately, the same way cmd.createProject/cmd.deleteProject
+// do, instead of going stale for up to completionCacheTTL.
+func runApply(c *ishell.Context) {
+	file, dryRun, prune, err := parseApplyArgs(c.Args)
+	if err != nil {
+		c.Err(err)
+		return
+	}
+
+	manifest, err := apply.LoadManifest(file, "")
+	if err != nil {
+		c.Err(err)
+		return
+	}
+
+	ctx, cancel := apiContext(c)
+	defer cancel()
+
+	current, err := currentState(ctx, manifest)
+	if err != nil {
+		c.Err(err)
+		return
+	}
+
+	plan := apply.Diff(manifest, current, prune)
+	if len(plan.Actions) == 0 {
+		c.Println("Nothing to do")
+		return
+	}
+
+	if dryRun || isInteractive(c) {
+		renderPlan(c, plan)
+	}
+	if dryRun {
+		return
+	}
+
+	if isInteractive(c) {
+		c.Print("Apply this plan? [y]/n ")
+		if !yesOrNo(c) {
+			c.Println("Apply aborted")
+			return
+		}
+	} else if !reflect.DeepEqual(c.Get(cYES), true) {
+		c.Err(errors.New("apply requires --yes when running non-interactively"))
+		return
+	}
+
+	if err := apply.Apply(ctx, plan, manifest, projectKeyCache.Invalidate); err != nil {
+		c.Err(err)
+		return
+	}
+	c.Printf("Applied %d change(s)\n", len(plan.Actions))
+}
+
+// currentState gathers the live LaunchDarkly state apply.Diff needs: every
+// project, plus, for the projects the manifest touches, their flags and each
+// of their environments' segments, which the API exposes as their own
+// collections rather than nested under a project.
+func currentState(ctx context.Context, manifest *apply.Manifest) (apply.CurrentState, error) {
+	projects, err := listProjects(ctx)
+	if err != nil {
+		return apply.CurrentState{}, err
+	}
+
+	state := apply.CurrentState{
+		Projects: projects,
+		Flags:    map[string][]ldapi.FeatureFlag{},
+		Segments: map[string][]ldapi.Segment{},
+	}
+
+	for _, project := range projects {
+		if findProjectSpecKey(manifest, project.Key) == nil {
+			continue
+		}
+
+		flags, _, err := api.Client.FeatureFlagsApi.GetFeatureFlags(ctx, api.Auth, project.Key, nil)
+		if err != nil {
+			return apply.CurrentState{}, err
+		}
+		state.Flags[project.Key] = flags.Items
+
+		for _, env := range project.Environments {
+			segments, _, err := api.Client.SegmentsApi.GetSegments(ctx, api.Auth, project.Key, env.Key)
+			if err != nil {
+				return apply.CurrentState{}, err
+			}
+			state.Segments[project.Key+"/"+env.Key] = segments.Items
+		}
+	}
+
+	return state, nil
+}
+
+func findProjectSpecKey(manifest *apply.Manifest, key string) *apply.ProjectSpec {
+	for _, spec := range manifest.Projects {
+		if spec.Key == key {
+			s := spec
+			return &s
+		}
+	}
+	return nil
+}
+
+func parseApplyArgs(args []string) (file string, dryRun bool, prune bool, err error) {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-f", "--file":
+			if i+1 >= len(args) {
+				return "", false, false, errTooFewArgs
+			}
+			i++
+			file = args[i]
+		case "--dry-run":
+			dryRun = true
+		case "--prune":
+			prune = true
+		default:
+			return "", false, false, errors.New("unknown argument: " + args[i])
+		}
+	}
+	if file == "" {
+		return "", false, false, errors.New("apply requires -f manifest.yaml")
+	}
+	return file, dryRun, prune, nil
+}
+
+func renderPlan(c *ishell.Context, plan *apply.Plan) {
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	table.SetHeader([]string{"Op", "Resource", "Key"})
+	for _, action := range plan.Actions {
+		key := action.Key
+		if action.Resource == "environment" {
+			key = action.Project + "/" + action.Key
+		}
+		table.Append([]string{action.Op, action.Resource, key})
+	}
+	table.SetRowLine(true)
+	table.Render()
+	renderPagedTable(c, buf)
+}
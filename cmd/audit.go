@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/util"
+)
+
+const auditPollInterval = 2 * time.Second
+
+func AddAuditCommands(shell *ishell.Shell) {
+	root := &ishell.Cmd{
+		Name: "audit",
+		Help: "list and follow audit log entries",
+		Func: listAuditTable,
+	}
+	root.AddCmd(&ishell.Cmd{
+		Name: "list",
+		Help: "list recent audit log entries",
+		Func: listAuditTable,
+	})
+	root.AddCmd(&ishell.Cmd{
+		Name: "tail",
+		Help: "stream audit log entries: audit tail [--follow] [--filter kind=flag,action=update] [--since 10m]",
+		Func: tailAudit,
+	})
+	shell.AddCmd(root)
+}
+
+func listAuditLogEntries(ctx context.Context, since time.Time) ([]ldapi.AuditLogEntry, error) {
+	entries, _, err := api.Client.AuditLogApi.GetAuditLogEntries(ctx, api.Auth, &ldapi.AuditLogEntriesOptions{
+		Project: api.CurrentProject,
+		Env:     api.CurrentEnvironment,
+		After:   since,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries.Items, nil
+}
+
+func listAuditTable(c *ishell.Context) {
+	ctx, cancel := apiContext(c)
+	defer cancel()
+
+	entries, err := listAuditLogEntries(ctx, time.Time{})
+	if err != nil {
+		c.Err(err)
+		return
+	}
+	if renderJSON(c) {
+		printJSON(c, entries)
+		return
+	}
+	renderAuditTable(c, entries)
+}
+
+func renderAuditTable(c *ishell.Context, entries []ldapi.AuditLogEntry) {
+	buf := bytes.Buffer{}
+	table := tablewriter.NewWriter(&buf)
+	table.SetHeader([]string{"Date", "Kind", "Name", "Comment"})
+	for _, entry := range entries {
+		table.Append([]string{entry.Date.String(), entry.Kind, entry.Name, entry.Comment})
+	}
+	table.SetRowLine(true)
+	table.Render()
+	renderPagedTable(c, buf)
+}
+
+// auditFilter is the parsed form of `--filter kind=flag,action=update`. An
+// empty field matches anything.
+type auditFilter struct {
+	kind   string
+	action string
+}
+
+func parseAuditFilter(raw string) auditFilter {
+	var f auditFilter
+	for _, pair := range strings.Split(raw, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "kind":
+			f.kind = kv[1]
+		case "action":
+			f.action = kv[1]
+		}
+	}
+	return f
+}
+
+func (f auditFilter) matches(entry ldapi.AuditLogEntry) bool {
+	if f.kind != "" && entry.Kind != f.kind {
+		return false
+	}
+	if f.action != "" && entry.Action != f.action {
+		return false
+	}
+	return true
+}
+
+type tailArgs struct {
+	follow bool
+	filter auditFilter
+	since  time.Time
+}
+
+func parseTailArgs(args []string) (tailArgs, error) {
+	t := tailArgs{since: time.Now()}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--follow":
+			t.follow = true
+		case "--filter":
+			if i+1 >= len(args) {
+				return t, errTooFewArgs
+			}
+			i++
+			t.filter = parseAuditFilter(args[i])
+		case "--since":
+			if i+1 >= len(args) {
+				return t, errTooFewArgs
+			}
+			i++
+			since, err := parseSince(args[i])
+			if err != nil {
+				return t, err
+			}
+			t.since = since
+		default:
+			return t, errors.New("unknown argument: " + args[i])
+		}
+	}
+	return t, nil
+}
+
+// parseSince accepts either a duration relative to now ("10m", "1h") or an
+// RFC3339 timestamp.
+func parseSince(raw string) (time.Time, error) {
+	if d, err := time.ParseDuration(raw); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// tailAudit streams audit log entries, printing only ones not already seen.
+// With --follow it keeps polling until the context is cancelled (Ctrl-C);
+// without it, it fetches one page and returns.
+func tailAudit(c *ishell.Context) {
+	args, err := parseTailArgs(c.Args)
+	if err != nil {
+		c.Err(err)
+		return
+	}
+
+	ctx, cancel := util.WithInterrupt(context.Background())
+	defer cancel()
+
+	seen := map[string]bool{}
+	since := args.since
+
+	for {
+		pollCtx, cancelPoll := apiContextWithParent(ctx, c)
+		entries, err := listAuditLogEntries(pollCtx, since)
+		cancelPoll()
+		if err != nil {
+			c.Err(err)
+			return
+		}
+
+		var fresh []ldapi.AuditLogEntry
+		for _, entry := range entries {
+			if seen[entry.Id] || !args.filter.matches(entry) {
+				continue
+			}
+			seen[entry.Id] = true
+			fresh = append(fresh, entry)
+			if entry.Date.After(since) {
+				since = entry.Date
+			}
+		}
+
+		if len(fresh) > 0 {
+			if renderJSON(c) {
+				printJSON(c, fresh)
+			} else {
+				renderAuditTable(c, fresh)
+			}
+		}
+
+		if !args.follow {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(auditPollInterval):
+		}
+	}
+}
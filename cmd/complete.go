@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/cmd/internal/complete"
+)
+
+const cCOMPLETION = "completion"
+
+const completionCacheTTL = 10 * time.Second
+
+// fuzzyCompletion mirrors jsonMode: a package-level switch rather than
+// something threaded through *ishell.Context, because Completer funcs are
+// called by ishell outside of any command's context.
+var fuzzyCompletion = true
+
+func setCompletionMode(c *ishell.Context) {
+	if len(c.Args) != 1 || (c.Args[0] != "prefix" && c.Args[0] != "fuzzy") {
+		c.Err(errors.New("usage: set completion prefix|fuzzy"))
+		return
+	}
+	fuzzyCompletion = c.Args[0] == "fuzzy"
+	c.Printf("completion set to %s\n", c.Args[0])
+}
+
+func AddCompletionCommands(shell *ishell.Shell, set *ishell.Cmd) {
+	set.AddCmd(&ishell.Cmd{
+		Name: cCOMPLETION,
+		Help: "choose how shell tab-completion matches: set completion prefix|fuzzy",
+		Func: setCompletionMode,
+	})
+}
+
+// completeKeys filters keys against the text typed so far using whichever
+// matcher `set completion` has selected, defaulting to fuzzy.
+func completeKeys(keys []string, args []string) []string {
+	prefix := toPrefix(args)
+	if !fuzzyCompletion {
+		return withPrefix(keys, prefix)
+	}
+	return complete.Fuzzy(keys, prefix)
+}
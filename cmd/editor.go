@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/mattbaird/jsonpatch"
+	"gopkg.in/yaml.v2"
+
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	ldapi "github.com/launchdarkly/api-client-go"
+)
+
+// Editor launches an interactive editor against a buffer and returns its
+// contents once the user is done. Production code uses execEditor, which
+// shells out to a real binary; tests substitute a fake that mutates the
+// buffer programmatically instead of launching a subprocess.
+type Editor interface {
+	Edit(data []byte, ext string) ([]byte, error)
+}
+
+// execEditor runs a resolved, real editor binary against a scratch file.
+// argv[0] is the resolved binary path; any remaining elements are fixed
+// arguments (e.g. "-w") that preceded the scratch file name on the command
+// line, so "code -w" resolves and runs the same way it would from a shell.
+type execEditor struct {
+	argv []string
+}
+
+// Edit writes data to a temp file named with ext (so editors that syntax
+// highlight by extension get the right mode), runs the editor against it,
+// and returns the file's contents afterwards. The scratch file lives under
+// $TMPDIR (via os.TempDir) rather than a hardcoded /tmp.
+func (e execEditor) Edit(data []byte, ext string) ([]byte, error) {
+	file, err := ioutil.TempFile("", "ldc*"+ext)
+	if err != nil {
+		return nil, err
+	}
+	name := file.Name()
+	defer os.Remove(name) // nolint:errcheck // best-effort cleanup of our own scratch file
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	argv := append(append([]string{}, e.argv...), name)
+	proc, err := os.StartProcess(e.argv[0], argv, &os.ProcAttr{Files: []*os.File{os.Stdin, os.Stdout, os.Stderr}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := proc.Wait(); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(name) // nolint:gosec // G304: ok, we created name above
+}
+
+// resolveEditor picks an editor binary, in order: the shell's `set editor`
+// value, $VISUAL, $EDITOR, then a platform fallback. Each candidate is split
+// on whitespace first so an editor configured with fixed arguments (e.g.
+// "code -w", "emacsclient -t") resolves its first token with exec.LookPath
+// and keeps the rest as argv, the same way a shell would run it.
+func resolveEditor(c shellIO) (Editor, error) {
+	candidates := []string{}
+	if name, ok := c.Get(cEDITOR).(string); ok && name != "" {
+		candidates = append(candidates, name)
+	}
+	candidates = append(candidates, os.Getenv("VISUAL"), os.Getenv("EDITOR"), platformDefaultEditor())
+
+	for _, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		path, err := exec.LookPath(fields[0])
+		if err != nil {
+			continue
+		}
+		return execEditor{argv: append([]string{path}, fields[1:]...)}, nil
+	}
+	return nil, fmt.Errorf("no editor found: set $VISUAL, $EDITOR, or `set editor <name>`")
+}
+
+func platformDefaultEditor() string {
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// serializer converts between the API's JSON representation and whatever
+// format the user is editing in.
+type serializer interface {
+	encode(data []byte) ([]byte, error)
+	decode(data []byte) ([]byte, error)
+	ext() string
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) encode(data []byte) ([]byte, error) { return data, nil }
+
+func (jsonSerializer) decode(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, annotateParseError(data, err)
+	}
+	return data, nil
+}
+
+func (jsonSerializer) ext() string { return ".json" }
+
+type yamlSerializer struct{}
+
+func (yamlSerializer) encode(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+func (yamlSerializer) decode(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, annotateParseError(data, err)
+	}
+	// yaml.v2 decodes mappings as map[interface{}]interface{}, which encoding/json rejects.
+	var toJSON func(interface{}) interface{}
+	toJSON = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[interface{}]interface{}:
+			m := make(map[string]interface{}, len(v))
+			for k, val := range v {
+				m[fmt.Sprint(k)] = toJSON(val)
+			}
+			return m
+		case []interface{}:
+			for i, val := range v {
+				v[i] = toJSON(val)
+			}
+		}
+		return v
+	}
+	return json.Marshal(toJSON(v))
+}
+
+func (yamlSerializer) ext() string { return ".yaml" }
+
+// serializerFor picks a serializer from the format name passed to editFile:
+// "yaml" selects YAML, anything else (including "") selects JSON. editFile has no file path to sniff an extension from (it edits an
+// in-memory resource, not a manifest on disk), so unlike LoadManifest this
+// has nothing to fall back to.
+func serializerFor(format string) serializer {
+	if format == "yaml" {
+		return yamlSerializer{}
+	}
+	return jsonSerializer{}
+}
+
+// annotateParseError adds a "line N:" prefix naming the offending line to a
+// JSON syntax error, whose default message reports only a byte offset. Any
+// other error, including every YAML error, is returned unchanged.
+func annotateParseError(data []byte, err error) error {
+	if serr, ok := err.(*json.SyntaxError); ok {
+		return fmt.Errorf("line %d: %s", lineAt(data, serr.Offset), err)
+	}
+	return err
+}
+
+func lineAt(data []byte, offset int64) int {
+	if offset < 0 || int(offset) > len(data) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// editFile opens original (JSON) for editing, round-tripping it through the
+// format named by format if non-JSON, and returns the JSON patch between the
+// original and the user's edits. format may be "" (meaning JSON).
+func editFile(c *ishell.Context, original []byte, format string) (patch *ldapi.PatchComment, err error) {
+	editor, err := resolveEditor(c)
+	if err != nil {
+		c.Err(err)
+		return nil, err
+	}
+	return editWithEditor(c, editor, original, format)
+}
+
+// editWithEditor is editFile's retry loop, taking an injected Editor and a
+// shellIO (rather than a concrete *ishell.Context) so tests can drive it with
+// a fake editor and a fake shell instead of a real subprocess and terminal.
+func editWithEditor(c shellIO, editor Editor, original []byte, format string) (patch *ldapi.PatchComment, err error) {
+	ser := serializerFor(format)
+
+	current, err := ser.encode(original)
+	if err != nil {
+		c.Err(err)
+		return nil, err
+	}
+
+	var patchOps []jsonpatch.JsonPatchOperation
+	for {
+		edited, err := editor.Edit(current, ser.ext())
+		if err != nil {
+			c.Err(err)
+			return nil, err
+		}
+
+		decoded, decodeErr := ser.decode(edited)
+		if decodeErr != nil {
+			c.Printf("Unable to parse: %s\n", decodeErr)
+			c.Print("Make changes? [y]/n ")
+			if !yesOrNo(c) {
+				c.Println("Edit aborted")
+				break
+			}
+			current = edited
+			continue
+		}
+
+		patchOps, err = jsonpatch.CreatePatch(original, decoded)
+		if err != nil {
+			patchOps = nil
+			c.Printf("Unable to create patch: %s\n", err.Error())
+			c.Print("Make changes? [y]/n ")
+			if !yesOrNo(c) {
+				c.Println("Edit aborted")
+				break
+			}
+			current = edited
+			continue
+		}
+
+		break
+	}
+
+	if len(patchOps) == 0 {
+		return nil, nil
+	}
+
+	var patchComment ldapi.PatchComment
+	for _, op := range patchOps {
+		op := op // avoid aliasing the range variable across iterations
+		patchComment.Patch = append(patchComment.Patch, ldapi.PatchOperation{
+			Op:    op.Operation,
+			Path:  op.Path,
+			Value: &op.Value,
+		})
+	}
+
+	c.Print("Enter comment: ")
+	patchComment.Comment = c.ReadLine()
+	return &patchComment, nil
+}
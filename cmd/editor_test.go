@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeShell is a shellIO driven entirely from queued ReadLine responses, so
+// editWithEditor's retry/abort prompts can be exercised without a terminal.
+type fakeShell struct {
+	values  map[string]interface{}
+	lines   []string
+	printed []string
+}
+
+func (f *fakeShell) Get(key string) interface{} { return f.values[key] }
+func (f *fakeShell) Print(a ...interface{})      { f.printed = append(f.printed, fmt.Sprint(a...)) }
+func (f *fakeShell) Printf(format string, a ...interface{}) {
+	f.printed = append(f.printed, fmt.Sprintf(format, a...))
+}
+func (f *fakeShell) Println(a ...interface{}) { f.printed = append(f.printed, fmt.Sprintln(a...)) }
+func (f *fakeShell) Err(err error)             { f.printed = append(f.printed, err.Error()) }
+func (f *fakeShell) ReadLine() string {
+	if len(f.lines) == 0 {
+		return ""
+	}
+	line := f.lines[0]
+	f.lines = f.lines[1:]
+	return line
+}
+
+// fakeEditor is an Editor that returns queued buffers instead of launching a
+// real subprocess, so editWithEditor's retry loop can be driven deterministically.
+type fakeEditor struct {
+	responses [][]byte
+	calls     int
+}
+
+func (f *fakeEditor) Edit(data []byte, ext string) ([]byte, error) {
+	if f.calls >= len(f.responses) {
+		return data, nil
+	}
+	out := f.responses[f.calls]
+	f.calls++
+	return out, nil
+}
+
+func TestEditWithEditorRetriesOnDecodeError(t *testing.T) {
+	editor := &fakeEditor{responses: [][]byte{
+		[]byte("not json"),
+		[]byte(`{"key":"a","name":"b"}`),
+	}}
+	shell := &fakeShell{lines: []string{"y", "updated the name"}}
+
+	patch, err := editWithEditor(shell, editor, []byte(`{"key":"a","name":"a"}`), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patch == nil || patch.Comment != "updated the name" {
+		t.Fatalf("expected a patch with the entered comment, got %+v", patch)
+	}
+	if editor.calls != 2 {
+		t.Fatalf("expected the editor to be re-invoked once after the decode error, got %d calls", editor.calls)
+	}
+}
+
+func TestEditWithEditorRetriesOnPatchError(t *testing.T) {
+	editor := &fakeEditor{responses: [][]byte{
+		[]byte(`"just a string"`),
+		[]byte(`{"key":"a","name":"b"}`),
+	}}
+	shell := &fakeShell{lines: []string{"y", "fixed it"}}
+
+	patch, err := editWithEditor(shell, editor, []byte(`{"key":"a","name":"a"}`), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patch == nil || patch.Comment != "fixed it" {
+		t.Fatalf("expected a patch after retrying the unpatchable edit, got %+v", patch)
+	}
+}
+
+func TestEditWithEditorAbortsWhenUserDeclinesRetry(t *testing.T) {
+	editor := &fakeEditor{responses: [][]byte{[]byte("not json")}}
+	shell := &fakeShell{lines: []string{"n"}}
+
+	patch, err := editWithEditor(shell, editor, []byte(`{"key":"a"}`), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patch != nil {
+		t.Fatalf("expected a nil patch on abort, got %+v", patch)
+	}
+	if editor.calls != 1 {
+		t.Fatalf("expected no retry once the user declined, got %d calls", editor.calls)
+	}
+}
+
+func TestEditWithEditorReturnsNilPatchWhenUnchanged(t *testing.T) {
+	original := []byte(`{"key":"a","name":"a"}`)
+	editor := &fakeEditor{responses: [][]byte{original}}
+	shell := &fakeShell{lines: []string{"should not be read"}}
+
+	patch, err := editWithEditor(shell, editor, original, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patch != nil {
+		t.Fatalf("expected no patch when the edited content is identical, got %+v", patch)
+	}
+}
+
+func TestSerializerForSelectsByFormat(t *testing.T) {
+	if _, ok := serializerFor("yaml").(yamlSerializer); !ok {
+		t.Fatalf("expected format \"yaml\" to select yamlSerializer")
+	}
+	if _, ok := serializerFor("").(jsonSerializer); !ok {
+		t.Fatalf("json should be the default when format is unset")
+	}
+}
+
+func TestYAMLSerializerRoundTripsThroughJSON(t *testing.T) {
+	ser := yamlSerializer{}
+
+	yamlBytes, err := ser.encode([]byte(`{"key":"abc","name":"ABC"}`))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !strings.Contains(string(yamlBytes), "key: abc") {
+		t.Fatalf("expected YAML output, got %q", yamlBytes)
+	}
+
+	jsonBytes, err := ser.decode(yamlBytes)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.Contains(string(jsonBytes), `"key":"abc"`) {
+		t.Fatalf("expected round-tripped JSON, got %q", jsonBytes)
+	}
+}
+
+func TestJSONSerializerDecodeReportsLineNumber(t *testing.T) {
+	bad := []byte("{\n  \"key\": \n}")
+
+	_, err := jsonSerializer{}.decode(bad)
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Fatalf("expected error to start with the offending line, got %q", err.Error())
+	}
+}
+
+func TestYAMLSerializerDecodeRejectsInvalidYAML(t *testing.T) {
+	_, err := yamlSerializer{}.decode([]byte("key: [unterminated"))
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
+
+// TestEditWithEditorSetsEachOpsOwnValue guards against aliasing the
+// jsonpatch range variable: an edit that changes more than one field must
+// not have every patch entry read back the last op's value.
+func TestEditWithEditorSetsEachOpsOwnValue(t *testing.T) {
+	editor := &fakeEditor{responses: [][]byte{
+		[]byte(`{"key":"a","name":"New Name","description":"New Description"}`),
+	}}
+	shell := &fakeShell{lines: []string{"a comment"}}
+
+	patch, err := editWithEditor(shell, editor, []byte(`{"key":"a","name":"Old Name","description":"Old Description"}`), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patch == nil || len(patch.Patch) != 2 {
+		t.Fatalf("expected 2 patch ops, got %+v", patch)
+	}
+	values := map[string]interface{}{}
+	for _, op := range patch.Patch {
+		values[op.Path] = *op.Value
+	}
+	if values["/name"] != "New Name" {
+		t.Fatalf("expected /name to read back its own value, got %+v", values)
+	}
+	if values["/description"] != "New Description" {
+		t.Fatalf("expected /description to read back its own value, got %+v", values)
+	}
+}
@@ -0,0 +1,57 @@
+// Package complete provides a TTL-memoized key cache and a fuzzy subsequence
+// matcher, shared by the project/environment/flag completers in cmd so that
+// every keystroke doesn't re-fetch from the API and so typing an abbreviation
+// like "prd-web" can still find "production-webapp".
+package complete
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache memoizes the result of a slow key-listing call (e.g. fetching every
+// project key from the API) for ttl. Invalidate forces the next call to
+// Keys to refetch, so command Funcs that create or delete a resource can
+// keep completion in sync without waiting out the TTL.
+type Cache struct {
+	ttl   time.Duration
+	fetch func() ([]string, error)
+
+	mu      sync.Mutex
+	keys    []string
+	fetched time.Time
+}
+
+// NewCache builds a Cache that calls fetch at most once per ttl.
+func NewCache(ttl time.Duration, fetch func() ([]string, error)) *Cache {
+	return &Cache{ttl: ttl, fetch: fetch}
+}
+
+// Keys returns the cached keys, refetching if nothing has been fetched yet
+// or the cache has gone stale. fetched, not keys, tracks that: a fetch that
+// legitimately returns zero keys must still be cached instead of refetched
+// on every call.
+func (c *Cache) Keys() ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetched.IsZero() && time.Since(c.fetched) < c.ttl {
+		return c.keys, nil
+	}
+
+	keys, err := c.fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	c.fetched = time.Now()
+	return keys, nil
+}
+
+// Invalidate clears the cache so the next Keys call refetches immediately.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = nil
+	c.fetched = time.Time{}
+}
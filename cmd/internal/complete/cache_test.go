@@ -0,0 +1,103 @@
+package complete
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+func TestCacheReusesResultWithinTTL(t *testing.T) {
+	calls := 0
+	cache := NewCache(time.Hour, func() ([]string, error) {
+		calls++
+		return []string{"a", "b"}, nil
+	})
+
+	if _, err := cache.Keys(); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	if _, err := cache.Keys(); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single fetch within the TTL, got %d", calls)
+	}
+}
+
+func TestCacheInvalidateForcesRefetch(t *testing.T) {
+	calls := 0
+	cache := NewCache(time.Hour, func() ([]string, error) {
+		calls++
+		return []string{"a", "b"}, nil
+	})
+
+	if _, err := cache.Keys(); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	cache.Invalidate()
+	if _, err := cache.Keys(); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected Invalidate to force a second fetch, got %d calls", calls)
+	}
+}
+
+func TestCacheRefetchesAfterTTLExpires(t *testing.T) {
+	calls := 0
+	cache := NewCache(time.Millisecond, func() ([]string, error) {
+		calls++
+		return []string{"a", "b"}, nil
+	})
+
+	if _, err := cache.Keys(); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := cache.Keys(); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected a stale cache to refetch, got %d calls", calls)
+	}
+}
+
+func TestCacheCachesLegitimatelyEmptyResult(t *testing.T) {
+	calls := 0
+	cache := NewCache(time.Hour, func() ([]string, error) {
+		calls++
+		return []string{}, nil
+	})
+
+	if _, err := cache.Keys(); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	if _, err := cache.Keys(); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a zero-key fetch to still be cached within the TTL, got %d calls", calls)
+	}
+}
+
+func TestCacheDoesNotCacheFetchErrors(t *testing.T) {
+	calls := 0
+	cache := NewCache(time.Hour, func() ([]string, error) {
+		calls++
+		return nil, errBoom
+	})
+
+	if _, err := cache.Keys(); err != errBoom {
+		t.Fatalf("expected the fetch error to be returned, got %v", err)
+	}
+	if _, err := cache.Keys(); err != errBoom {
+		t.Fatalf("expected a failed fetch to be retried rather than cached, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected both calls to re-fetch after an error, got %d", calls)
+	}
+}
@@ -0,0 +1,91 @@
+package complete
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	consecutiveBonus = 5
+	startOfWordBonus = 10
+)
+
+// Fuzzy returns every key in keys whose characters contain query as a
+// case-insensitive subsequence, ordered best-match-first. Consecutive
+// matched characters and matches that start a "word" (the beginning of the
+// key, or right after a -, _, ., / or space) score higher; ties are broken
+// by shorter key. An empty query matches everything, alphabetically.
+func Fuzzy(keys []string, query string) []string {
+	if query == "" {
+		sorted := append([]string(nil), keys...)
+		sort.Strings(sorted)
+		return sorted
+	}
+
+	type match struct {
+		key   string
+		score int
+	}
+	var matches []match
+	for _, key := range keys {
+		if s, ok := score(key, query); ok {
+			matches = append(matches, match{key: key, score: s})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].key) < len(matches[j].key)
+	})
+
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.key
+	}
+	return result
+}
+
+func isSeparator(b byte) bool {
+	switch b {
+	case '-', '_', '.', '/', ' ':
+		return true
+	}
+	return false
+}
+
+// score performs a case-insensitive subsequence match of query against key,
+// returning a score and whether every character in query was found in
+// order. A higher score means a better match.
+func score(key, query string) (int, bool) {
+	k := strings.ToLower(key)
+	q := strings.ToLower(query)
+
+	points := 0
+	ki := 0
+	prevMatched := false
+	for qi := 0; qi < len(q); qi++ {
+		found := false
+		for ; ki < len(k); ki++ {
+			if k[ki] != q[qi] {
+				prevMatched = false
+				continue
+			}
+			if ki == 0 || isSeparator(k[ki-1]) {
+				points += startOfWordBonus
+			}
+			if prevMatched {
+				points += consecutiveBonus
+			}
+			prevMatched = true
+			found = true
+			ki++
+			break
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return points, true
+}
@@ -0,0 +1,54 @@
+package complete
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFuzzyMatchesSubsequence(t *testing.T) {
+	keys := []string{"production-webapp", "production-api", "staging-webapp"}
+
+	got := Fuzzy(keys, "prd-web")
+	if len(got) == 0 || got[0] != "production-webapp" {
+		t.Fatalf("expected production-webapp to rank first, got %v", got)
+	}
+}
+
+func TestFuzzyExcludesNonMatches(t *testing.T) {
+	keys := []string{"production-webapp", "staging-webapp"}
+
+	got := Fuzzy(keys, "xyz")
+	if len(got) != 0 {
+		t.Fatalf("expected no matches, got %v", got)
+	}
+}
+
+func TestFuzzyPrefersShorterKeyOnTie(t *testing.T) {
+	keys := []string{"abc-long-tail", "abc"}
+
+	got := Fuzzy(keys, "abc")
+	if len(got) != 2 || got[0] != "abc" {
+		t.Fatalf("expected shorter exact key to rank first, got %v", got)
+	}
+}
+
+func TestFuzzyEmptyQueryReturnsAllSorted(t *testing.T) {
+	keys := []string{"banana", "apple"}
+
+	got := Fuzzy(keys, "")
+	if len(got) != 2 || got[0] != "apple" || got[1] != "banana" {
+		t.Fatalf("expected alphabetical order, got %v", got)
+	}
+}
+
+func BenchmarkFuzzy(b *testing.B) {
+	keys := make([]string, 5000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("project-%d-webapp", i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Fuzzy(keys, "prj-web")
+	}
+}
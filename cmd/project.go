@@ -2,16 +2,26 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"strings"
 
-	"github.com/abiosoft/ishell"
 	"github.com/olekukonko/tablewriter"
+	ishell "gopkg.in/abiosoft/ishell.v2"
 
 	"github.com/launchdarkly/api-client-go"
 	"github.com/launchdarkly/ldc/api"
+	"github.com/launchdarkly/ldc/cmd/internal/complete"
 )
 
+// projectKeyCache memoizes project keys for completion so every keystroke
+// doesn't fetch the project list; invalidated whenever the set of projects
+// changes.
+var projectKeyCache = complete.NewCache(completionCacheTTL, func() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	return listProjectKeys(ctx)
+})
+
 func AddProjectCommands(shell *ishell.Shell) {
 	root := &ishell.Cmd{
 		Name:    "projects",
@@ -54,18 +64,18 @@ func AddProjectCommands(shell *ishell.Shell) {
 	shell.AddCmd(root)
 }
 
-func listProjects() ([]ldapi.Project, error) {
-	projects, _, err := api.Client.ProjectsApi.GetProjects(api.Auth)
+func listProjects(ctx context.Context) ([]ldapi.Project, error) {
+	projects, _, err := api.Client.ProjectsApi.GetProjects(ctx, api.Auth)
 	if err != nil {
 		return nil, err
 	}
 	return projects.Items, nil
 }
 
-func listProjectKeys() ([]string, error) {
+func listProjectKeys(ctx context.Context) ([]string, error) {
 	//TODO errors
 	var keys []string
-	projects, _, err := api.Client.ProjectsApi.GetProjects(api.Auth)
+	projects, _, err := api.Client.ProjectsApi.GetProjects(ctx, api.Auth)
 	if err != nil {
 		return nil, err
 	}
@@ -76,9 +86,15 @@ func listProjectKeys() ([]string, error) {
 }
 
 func listProjectsTable(c *ishell.Context) {
-	projects, err := listProjects()
+	ctx, cancel := apiContext(c)
+	defer cancel()
+	projects, err := listProjects(ctx)
 	if err != nil {
-		c.Err(err)
+		outputFor(c).Err(err)
+		return
+	}
+	if renderJSON(c) {
+		printJSON(c, projects)
 		return
 	}
 	buf := bytes.Buffer{}
@@ -89,11 +105,7 @@ func listProjectsTable(c *ishell.Context) {
 	}
 	table.SetRowLine(true)
 	table.Render()
-	if buf.Len() > 1000 {
-		c.ShowPaged(buf.String())
-	} else {
-		c.Print(buf.String())
-	}
+	renderPagedTable(c, buf)
 }
 
 func switchToProject(c *ishell.Context, project *ldapi.Project) {
@@ -112,25 +124,19 @@ func switchToProject(c *ishell.Context, project *ldapi.Project) {
 }
 
 func projectCompleter(args []string) []string {
-	var completions []string
-	// TODO caching?
-	keys, err := listProjectKeys()
+	keys, err := projectKeyCache.Keys()
 	if err != nil {
 		return nil
 	}
-	for _, key := range keys {
-		// fuzzy?
-		if len(args) == 0 || strings.HasPrefix(key, args[0]) {
-			completions = append(completions, key)
-		}
-	}
-	return completions
+	return completeKeys(keys, args)
 }
 
 func getProjectArg(c *ishell.Context) *ldapi.Project {
-	projects, err := listProjects()
+	ctx, cancel := apiContext(c)
+	defer cancel()
+	projects, err := listProjects(ctx)
 	if err != nil {
-		c.Err(err)
+		outputFor(c).Err(err)
 		return nil
 	}
 	var foundProject *ldapi.Project
@@ -143,13 +149,16 @@ func getProjectArg(c *ishell.Context) *ldapi.Project {
 			}
 		}
 		if foundProject == nil {
-			c.Printf("Project %s does not exist\n", projectKey)
+			outputFor(c).Line("Project %s does not exist\n", projectKey)
 		}
+	} else if !isInteractive(c) {
+		outputFor(c).Err(errTooFewArgs)
+		return nil
 	} else {
 		// TODO LOL
-		options, err := listProjectKeys()
+		options, err := listProjectKeys(ctx)
 		if err != nil {
-			c.Err(err)
+			outputFor(c).Err(err)
 			return nil
 		}
 		choice := c.MultiChoice(options, "Choose a project")
@@ -162,7 +171,7 @@ func createProject(c *ishell.Context) {
 	var key, name string
 	switch len(c.Args) {
 	case 0:
-		c.Err(errors.New("please supply at least a key for the new environment"))
+		outputFor(c).Err(errors.New("please supply at least a key for the new project"))
 		return
 	case 1:
 		key = c.Args[0]
@@ -171,17 +180,26 @@ func createProject(c *ishell.Context) {
 		key = c.Args[0]
 		name = c.Args[1]
 	default:
-		c.Err(errors.New("too many arguments.  Expected arguments are: key [name]."))
+		outputFor(c).Err(errors.New("too many arguments.  Expected arguments are: key [name]."))
 		return
 	}
-	if _, err := api.Client.ProjectsApi.PostProject(api.Auth, ldapi.ProjectBody{Key: key, Name: name}); err != nil {
-		c.Err(err)
+	ctx, cancel := apiContext(c)
+	defer cancel()
+	if _, err := api.Client.ProjectsApi.PostProject(ctx, api.Auth, ldapi.ProjectBody{Key: key, Name: name}); err != nil {
+		outputFor(c).Err(err)
 		return
 	}
-	c.Printf("Created project %s\n", key)
-	project, _, err := api.Client.ProjectsApi.GetProject(api.Auth, key)
+	projectKeyCache.Invalidate()
+	outputFor(c).Line("Created project %s\n", key)
+
+	if !isInteractive(c) {
+		// Switching the shell's current project/prompt only means something
+		// for the interactive session; a scripted `projects create` is done.
+		return
+	}
+	project, _, err := api.Client.ProjectsApi.GetProject(ctx, api.Auth, key)
 	if err != nil {
-		c.Err(err)
+		outputFor(c).Err(err)
 		return
 	}
 	switchToProject(c, &project)
@@ -189,23 +207,27 @@ func createProject(c *ishell.Context) {
 
 func deleteProject(c *ishell.Context) {
 	project := getProjectArg(c)
-	if project != nil {
+	if project == nil {
 		return
 	}
-	confirmDelete(c, "project key", project.Key)
-	if project != nil {
-		_, err := api.Client.ProjectsApi.DeleteProject(api.Auth, project.Key)
-		if err != nil {
-			c.Err(err)
-			return
-		}
-		c.Printf("Deleted project %s\n", project.Key)
+	if !confirmDelete(c, "project key", project.Key) {
+		outputFor(c).Line("Delete aborted\n")
+		return
+	}
+	ctx, cancel := apiContext(c)
+	defer cancel()
+	_, err := api.Client.ProjectsApi.DeleteProject(ctx, api.Auth, project.Key)
+	if err != nil {
+		outputFor(c).Err(err)
+		return
 	}
+	projectKeyCache.Invalidate()
+	outputFor(c).Line("Deleted project %s\n", project.Key)
 }
 
 func updateProject(c *ishell.Context) {
 	//???
 	// this sucks, json patch
-	//api.Client.ProjectsApi.PatchProject(api.Auth, "abc"
+	//api.Client.ProjectsApi.PatchProject(ctx, api.Auth, "abc"
 
 }
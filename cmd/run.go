@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/urfave/cli"
+	ishell "gopkg.in/abiosoft/ishell.v2"
+)
+
+// RunOptions controls a single non-interactive invocation of ldc. JSON is a
+// pointer because "not passed" (inherit renderJSON's scripted default of
+// JSON output) and "explicitly --json=false" (force a table) are different
+// states; Run only sets it when the --json flag was actually parsed.
+type RunOptions struct {
+	JSON    *bool
+	Yes     bool
+	Timeout time.Duration
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// Run parses args as a one-shot command invocation (e.g. `ldc projects
+// list`) and calls the same command Funcs the interactive shell registers
+// (listProjectsTable, createProject, deleteProject, ...) against a
+// non-interactive *ishell.Context, so there's exactly one implementation of
+// each command's behavior. It returns a process exit code.
+func Run(args []string, opts RunOptions) int {
+	app := cli.NewApp()
+	app.Name = "ldc"
+	app.Usage = "LaunchDarkly command line client"
+	app.HideVersion = true
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{Name: "json", Usage: "print machine-readable JSON instead of a table"},
+		cli.BoolFlag{Name: "yes", Usage: "answer yes to any confirmation prompts"},
+		cli.DurationFlag{Name: "timeout", Usage: "deadline for each API call (overrides LDC_TIMEOUT)"},
+	}
+	app.Commands = []cli.Command{
+		projectsCommand(&opts),
+	}
+	app.Before = func(cc *cli.Context) error {
+		if cc.IsSet("json") {
+			v := cc.Bool("json")
+			opts.JSON = &v
+		}
+		if cc.IsSet("yes") {
+			opts.Yes = cc.Bool("yes")
+		}
+		if cc.IsSet("timeout") {
+			opts.Timeout = cc.Duration("timeout")
+		}
+		return nil
+	}
+
+	exitCode := 0
+	app.Writer = opts.stdout()
+	app.ErrWriter = opts.stderr()
+	app.CommandNotFound = func(c *cli.Context, command string) {
+		fmt.Fprintf(opts.stderr(), "ldc: no such command %q\n", command)
+		exitCode = 1
+	}
+
+	if err := app.Run(append([]string{"ldc"}, args...)); err != nil {
+		fmt.Fprintln(opts.stderr(), err)
+		return 1
+	}
+	return exitCode
+}
+
+func (o RunOptions) stdout() io.Writer {
+	if o.Stdout != nil {
+		return o.Stdout
+	}
+	return os.Stdout
+}
+
+func (o RunOptions) stderr() io.Writer {
+	if o.Stderr != nil {
+		return o.Stderr
+	}
+	return os.Stderr
+}
+
+// scriptContext builds the *ishell.Context a command Func expects, with
+// cINTERACTIVE left unset so confirmDelete, getProjectArg and renderJSON all
+// take their non-interactive branches. cJSON is only set when the caller
+// explicitly passed --json, so renderJSON's scripted default (JSON unless
+// told otherwise) still applies when it wasn't passed.
+func scriptContext(opts *RunOptions, args []string) *ishell.Context {
+	c := &ishell.Context{Args: args}
+	if opts.JSON != nil {
+		c.Set(cJSON, *opts.JSON)
+	}
+	c.Set(cYES, opts.Yes)
+	c.Set(cSTDOUT, opts.stdout())
+	c.Set(cSTDERR, opts.stderr())
+	if opts.Timeout > 0 {
+		c.Set(cTIMEOUT, opts.Timeout)
+	}
+	return c
+}
+
+func projectsCommand(opts *RunOptions) cli.Command {
+	return cli.Command{
+		Name:  "projects",
+		Usage: "list and operate on projects",
+		Subcommands: []cli.Command{
+			{
+				Name:  "list",
+				Usage: "list projects",
+				Action: func(cc *cli.Context) error {
+					listProjectsTable(scriptContext(opts, cc.Args()))
+					return nil
+				},
+			},
+			{
+				Name:  "create",
+				Usage: "create a project: projects create key [name]",
+				Action: func(cc *cli.Context) error {
+					createProject(scriptContext(opts, cc.Args()))
+					return nil
+				},
+			},
+			{
+				Name:  "delete",
+				Usage: "delete a project: projects delete key",
+				Action: func(cc *cli.Context) error {
+					deleteProject(scriptContext(opts, cc.Args()))
+					return nil
+				},
+			},
+		},
+	}
+}
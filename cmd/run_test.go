@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScriptContextLeavesJSONUnsetByDefault(t *testing.T) {
+	opts := &RunOptions{}
+	c := scriptContext(opts, nil)
+
+	if !renderJSON(c) {
+		t.Fatalf("expected scripted invocations to default to JSON output when --json wasn't passed")
+	}
+}
+
+func TestScriptContextHonorsExplicitJSONFalse(t *testing.T) {
+	no := false
+	opts := &RunOptions{JSON: &no}
+	c := scriptContext(opts, nil)
+
+	if renderJSON(c) {
+		t.Fatalf("expected --json=false to force table output")
+	}
+}
+
+func TestScriptContextHonorsExplicitJSONTrue(t *testing.T) {
+	yes := true
+	opts := &RunOptions{JSON: &yes}
+	c := scriptContext(opts, nil)
+
+	if !renderJSON(c) {
+		t.Fatalf("expected --json=true to force JSON output")
+	}
+}
+
+func TestScriptContextRequiresYesForDelete(t *testing.T) {
+	opts := &RunOptions{}
+	c := scriptContext(opts, nil)
+
+	if confirmDelete(c, "project key", "doomed") {
+		t.Fatalf("expected delete to be refused without --yes")
+	}
+}
+
+func TestScriptContextYesAllowsDelete(t *testing.T) {
+	opts := &RunOptions{Yes: true}
+	c := scriptContext(opts, nil)
+
+	if !confirmDelete(c, "project key", "doomed") {
+		t.Fatalf("expected --yes to allow a non-interactive delete")
+	}
+}
+
+// TestScriptContextRoutesOutputToRunOptionsWriters guards against the class
+// of bug where a command writes via the bare *ishell.Context scriptContext
+// builds (c.Printf/c.Err) instead of outputFor(c): that context has no real
+// *ishell.Shell behind it, so those calls panic outside of Run. Every
+// command reachable from Run must go through outputFor instead.
+func TestScriptContextRoutesOutputToRunOptionsWriters(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	opts := &RunOptions{Stdout: &stdout, Stderr: &stderr}
+	c := scriptContext(opts, nil)
+
+	outputFor(c).Line("created %s\n", "proj")
+	outputFor(c).Err(errTooFewArgs)
+
+	if stdout.String() != "created proj\n" {
+		t.Fatalf("expected the line to land on RunOptions.Stdout, got %q", stdout.String())
+	}
+	if stderr.Len() == 0 {
+		t.Fatalf("expected the error to land on RunOptions.Stderr")
+	}
+}
+
+// TestCreateProjectValidatesArgsWithoutPanicking exercises createProject
+// against a bare scriptContext the way Run's "projects create" action does:
+// with no api.Client configured, the only safe assertion is that argument
+// validation reports its error through Output (not c.Err on an unbacked
+// context) before ever reaching the API call.
+func TestCreateProjectValidatesArgsWithoutPanicking(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	opts := &RunOptions{Stdout: &stdout, Stderr: &stderr}
+
+	createProject(scriptContext(opts, nil))
+
+	if stderr.Len() == 0 {
+		t.Fatalf("expected the missing-key error to be written to RunOptions.Stderr")
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("expected nothing written to stdout, got %q", stdout.String())
+	}
+}
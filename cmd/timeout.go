@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	ishell "gopkg.in/abiosoft/ishell.v2"
+
+	"github.com/launchdarkly/ldc/cmd/util"
+)
+
+const cTIMEOUT = "timeout"
+
+const defaultAPITimeout = 30 * time.Second
+
+// defaultTimeout is the deadline used for any command that hasn't `set
+// timeout`'d its own. It can be overridden for the whole process via
+// LDC_TIMEOUT, e.g. LDC_TIMEOUT=90s ldc projects list.
+var defaultTimeout = defaultAPITimeout
+
+func init() {
+	if raw := os.Getenv("LDC_TIMEOUT"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			defaultTimeout = d
+		}
+	}
+}
+
+func AddTimeoutCommands(shell *ishell.Shell, set *ishell.Cmd) {
+	set.AddCmd(&ishell.Cmd{
+		Name: "timeout",
+		Help: "set the deadline for API calls: set timeout 30s",
+		Func: setTimeout,
+	})
+}
+
+func setTimeout(c *ishell.Context) {
+	if len(c.Args) != 1 {
+		c.Err(errors.New("usage: set timeout <duration>, e.g. set timeout 30s"))
+		return
+	}
+	d, err := time.ParseDuration(c.Args[0])
+	if err != nil {
+		c.Err(err)
+		return
+	}
+	c.Set(cTIMEOUT, d)
+	c.Printf("timeout set to %s\n", d)
+}
+
+func shellTimeout(c *ishell.Context) time.Duration {
+	if d, ok := c.Get(cTIMEOUT).(time.Duration); ok {
+		return d
+	}
+	return defaultTimeout
+}
+
+// apiContext returns a context for a single api.Client call: it carries a
+// deadline (from `set timeout`, LDC_TIMEOUT, or defaultAPITimeout) backed by
+// a resettable util.Deadline rather than a one-shot context.WithTimeout, and
+// is cancelled if the user hits Ctrl-C while the request is in flight, so a
+// hung LaunchDarkly endpoint never freezes the shell with no way to abort.
+func apiContext(c *ishell.Context) (context.Context, context.CancelFunc) {
+	deadline := util.NewDeadline(context.Background(), shellTimeout(c))
+	ctx, cancelInterrupt := util.WithInterrupt(deadline.Context())
+	return ctx, func() {
+		cancelInterrupt()
+		deadline.Stop()
+	}
+}
+
+// apiContextWithParent is apiContext for a call that's already running
+// inside a longer-lived, separately-cancellable context (e.g. one poll of
+// `audit tail`'s loop), so the SIGINT handler isn't registered a second time.
+func apiContextWithParent(parent context.Context, c *ishell.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, shellTimeout(c))
+}
@@ -4,16 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
-	"os"
-	"os/exec"
+	"fmt"
+	"io"
 	"reflect"
 	"strings"
 
-	"github.com/mattbaird/jsonpatch"
 	ishell "gopkg.in/abiosoft/ishell.v2"
 
-	ldapi "github.com/launchdarkly/api-client-go"
 	"github.com/launchdarkly/ldc/cmd/internal/path"
 )
 
@@ -21,8 +18,23 @@ const (
 	cINTERACTIVE = "interactive"
 	cEDITOR      = "editor"
 	cJSON        = "json"
+	cYES         = "yes"
+	cSTDOUT      = "stdout" // internal: io.Writer a non-interactive Run() prints to
+	cSTDERR      = "stderr" // internal: io.Writer a non-interactive Run() prints errors to
 )
 
+// shellIO is the subset of *ishell.Context the prompting and editing helpers
+// need, factored out so tests can drive them with a fake instead of a real
+// shell attached to a terminal.
+type shellIO interface {
+	Get(key string) interface{}
+	Print(a ...interface{})
+	Printf(format string, a ...interface{})
+	Println(a ...interface{})
+	ReadLine() string
+	Err(err error)
+}
+
 var errTooManyArgs = errors.New("too many arguments")
 var errTooFewArgs = errors.New("too few arguments")
 var errNotFound = errors.New("not found")
@@ -30,7 +42,9 @@ var errAborted = errors.New("aborted")
 
 func confirmDelete(c *ishell.Context, name string, expectedValue string) bool {
 	if !isInteractive(c) {
-		return true
+		// Scripted/CI invocations never get a terminal to re-type into, so
+		// deletion has to be opted into explicitly with --yes.
+		return reflect.DeepEqual(c.Get(cYES), true)
 	}
 	c.Printf("Re-enter the %s '%s' to delete: ", name, expectedValue)
 	value := c.ReadLine()
@@ -82,106 +96,6 @@ func nonFinalCompleter(completer func(args []string) []string) func(args []strin
 	}
 }
 
-func editFile(c *ishell.Context, original []byte) (patch *ldapi.PatchComment, err error) {
-	editor := c.Get(cEDITOR).(string)
-	cmd := exec.Command("command", "-v", editor) // nolint:gosec // ok to launch subprocess with variable
-	editorPathRaw, err := cmd.Output()
-	if err != nil {
-		c.Err(err)
-		return nil, err
-	}
-	editorPath := strings.TrimSpace(string(editorPathRaw))
-
-	var patchOps []jsonpatch.JsonPatchOperation
-	current := original
-	for {
-		file, err := ioutil.TempFile("/tmp", "ldc")
-		if err != nil {
-			c.Err(err)
-			return nil, err
-		}
-		name := file.Name()
-		_, err = file.Write(current)
-		if err != nil {
-			c.Err(err)
-			return nil, err
-		}
-		if err := file.Close(); err != nil {
-			c.Err(err)
-			return nil, err
-		}
-
-		proc, err := os.StartProcess(editorPath, []string{editor, name}, &os.ProcAttr{Files: []*os.File{os.Stdin, os.Stdout, os.Stderr}})
-		if err != nil {
-			return nil, err
-		}
-		if _, err := proc.Wait(); err != nil {
-			c.Err(err)
-			return nil, err
-		}
-
-		file, err = os.Open(name) // nolint:gosec // G304: Potential file inclusion via variable // ok because we created name
-		if err != nil {
-			return nil, err
-		}
-
-		newData, fileErr := ioutil.ReadAll(file)
-
-		err = os.Remove(name)
-		if err != nil {
-			c.Println("Unable to delete temporary file: %s", err)
-		}
-
-		if fileErr != nil {
-			c.Println("Unable to read file: %s", err)
-			c.Print("Try again? [y]/n  ")
-			if !yesOrNo(c) {
-				c.Println("Edit aborted")
-				break
-			}
-		}
-		if err := file.Close(); err != nil {
-			return nil, err
-		}
-
-		patchOps, err = jsonpatch.CreatePatch(original, newData)
-		if err != nil {
-			patchOps = nil
-			if err.Error() == "Invalid JSON Document" {
-				c.Print("Unable to parse json. Make changes? [y]/n ")
-			} else {
-				c.Printf("Unable to create patch: %s\n", err.Error())
-				c.Print("Make changes? [y]/n ")
-			}
-			if !yesOrNo(c) {
-				c.Println("Edit aborted")
-				break
-			}
-			current = newData
-			continue
-		}
-
-		break
-	}
-
-	if len(patchOps) == 0 {
-		return nil, nil
-	}
-
-	var patchComment ldapi.PatchComment
-	for _, op := range patchOps {
-		patchComment.Patch = append(patchComment.Patch, ldapi.PatchOperation{
-			Op:    op.Operation,
-			Path:  op.Path,
-			Value: &op.Value,
-		})
-	}
-
-	c.Print("Enter comment: ")
-	patchComment.Comment = c.ReadLine()
-	return &patchComment, nil
-}
-
 func firstOrEmpty(args []string) string {
 	if len(args) == 0 {
 		return ""
@@ -189,7 +103,7 @@ func firstOrEmpty(args []string) string {
 	return args[0]
 }
 
-func yesOrNo(c *ishell.Context) (yes bool) {
+func yesOrNo(c shellIO) (yes bool) {
 	val := c.ReadLine()
 	if val == "" || strings.ToLower(val) == "y" {
 		return true
@@ -207,6 +121,11 @@ func renderJSON(c *ishell.Context) bool {
 	if jsonMode != nil {
 		return *jsonMode
 	}
+	if !isInteractive(c) {
+		// Scripted invocations have no table to look at, so default to
+		// machine-parseable output unless a renderer has already been chosen.
+		return !reflect.DeepEqual(c.Get(cJSON), false)
+	}
 	return reflect.DeepEqual(c.Get(cJSON), true)
 }
 
@@ -214,22 +133,102 @@ func isInteractive(c *ishell.Context) bool {
 	return reflect.DeepEqual(c.Get(cINTERACTIVE), true)
 }
 
-func renderPagedTable(c *ishell.Context, buf bytes.Buffer) {
+// Output is how a command renders its results and status. The interactive
+// shell pages long tables and writes straight to the terminal; a scripted
+// invocation (cmd.Run) has no shell or terminal behind it and wants its
+// output on the writers RunOptions named instead. Every call site that
+// writes something a user or script might see (listProjectsTable,
+// createProject, deleteProject, renderPagedTable, printJSON, ...) should go
+// through this instead of talking to *ishell.Context directly, since a bare
+// *ishell.Context built by scriptContext has no shell to write through.
+type Output interface {
+	Table(buf bytes.Buffer)
+	JSON(data interface{}) error
+	Line(format string, a ...interface{})
+	Err(err error)
+}
+
+// shellOutput is the interactive behavior: page long tables, print straight
+// to the shell.
+type shellOutput struct {
+	c *ishell.Context
+}
+
+func (o shellOutput) Table(buf bytes.Buffer) {
 	if buf.Len() > 1000 {
-		c.Err(c.ShowPaged(buf.String()))
+		o.c.Err(o.c.ShowPaged(buf.String()))
 	} else {
-		c.Print(buf.String())
+		o.c.Print(buf.String())
 	}
 }
 
-func printJSON(c *ishell.Context, data interface{}) {
-	bytes, err := json.MarshalIndent(data, "", "  ")
+func (o shellOutput) JSON(data interface{}) error {
+	encoded, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		c.Err(err)
-		return
+		return err
 	}
+	o.c.Print(string(encoded) + "\n")
+	return nil
+}
 
-	c.Print(string(bytes) + "\n")
+func (o shellOutput) Line(format string, a ...interface{}) {
+	o.c.Printf(format, a...)
+}
+
+func (o shellOutput) Err(err error) {
+	o.c.Err(err)
+}
+
+// stdoutOutput is cmd.Run's renderer: write straight to the process's
+// stdout/stderr (or whatever RunOptions.Stdout/Stderr name), with no paging.
+type stdoutOutput struct {
+	w    io.Writer
+	errW io.Writer
+}
+
+func (o stdoutOutput) Table(buf bytes.Buffer) {
+	io.Copy(o.w, &buf) // nolint:errcheck // best-effort write to stdout
+}
+
+func (o stdoutOutput) JSON(data interface{}) error {
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(o.w, string(encoded))
+	return err
+}
+
+func (o stdoutOutput) Line(format string, a ...interface{}) {
+	fmt.Fprintf(o.w, format, a...) // nolint:errcheck // best-effort write to stdout
+}
+
+func (o stdoutOutput) Err(err error) {
+	fmt.Fprintln(o.errW, err) // nolint:errcheck // best-effort write to stderr
+}
+
+// outputFor picks the renderer for the current context: stdoutOutput for a
+// non-interactive cmd.Run invocation (which stashes its writers under
+// cSTDOUT/cSTDERR), shellOutput otherwise.
+func outputFor(c *ishell.Context) Output {
+	if w, ok := c.Get(cSTDOUT).(io.Writer); ok && w != nil {
+		errW, ok := c.Get(cSTDERR).(io.Writer)
+		if !ok || errW == nil {
+			errW = w
+		}
+		return stdoutOutput{w: w, errW: errW}
+	}
+	return shellOutput{c: c}
+}
+
+func renderPagedTable(c *ishell.Context, buf bytes.Buffer) {
+	outputFor(c).Table(buf)
+}
+
+func printJSON(c *ishell.Context, data interface{}) {
+	if err := outputFor(c).JSON(data); err != nil {
+		outputFor(c).Err(err)
+	}
 }
 
 func ifNotBlank(s string, defaultValue string) string {
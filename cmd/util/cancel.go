@@ -0,0 +1,111 @@
+// Package util holds small helpers shared across cmd's long-running
+// commands, as opposed to the per-shell-command helpers in cmd itself.
+package util
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// interrupted is closed each time the process receives an interrupt signal
+// (Ctrl-C), then immediately replaced with a fresh channel so the next
+// signal has something to close. Every context returned by WithInterrupt
+// selects on whichever channel is current at the time it's called instead of
+// each registering its own signal.Notify/goroutine pair, so N concurrent API
+// calls cost one os/signal registration, not N. Re-arming matters because
+// the shell keeps running commands after a Ctrl-C (e.g. to stop `audit tail
+// --follow`); a one-shot channel would leave every later command cancelled
+// before it starts.
+var (
+	watchInterruptOnce sync.Once
+	interruptMu        sync.Mutex
+	interrupted        = make(chan struct{})
+)
+
+func watchInterrupt() {
+	watchInterruptOnce.Do(func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		go func() {
+			for range sig {
+				interruptMu.Lock()
+				close(interrupted)
+				interrupted = make(chan struct{})
+				interruptMu.Unlock()
+			}
+		}()
+	})
+}
+
+// currentInterrupt returns the channel that will be closed by the next
+// interrupt signal.
+func currentInterrupt() chan struct{} {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+	return interrupted
+}
+
+// WithInterrupt returns a child of parent that is cancelled when the caller
+// invokes the returned CancelFunc, or when the process receives an interrupt
+// signal (Ctrl-C). It's meant for commands like `audit tail` that poll in a
+// loop and otherwise have no way for the user to abort cleanly.
+func WithInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	watchInterrupt()
+	ctx, cancel := context.WithCancel(parent)
+
+	ch := currentInterrupt()
+	go func() {
+		select {
+		case <-ch:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// Deadline is a resettable timeout, modeled on net.Conn's SetDeadline: Reset
+// re-arms the same underlying timer for a new duration instead of a caller
+// tearing down its context and building a fresh one, so code that wants to
+// extend a deadline mid-request doesn't have to rebuild the context tree
+// rooted under it. A deadline cannot be removed while keeping the context
+// alive: Stop disarms the timer but also cancels the context.
+type Deadline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	timer  *time.Timer
+}
+
+// NewDeadline returns a Deadline whose context is cancelled after d unless
+// Reset or Stop is called first.
+func NewDeadline(parent context.Context, d time.Duration) *Deadline {
+	ctx, cancel := context.WithCancel(parent)
+	return &Deadline{
+		ctx:    ctx,
+		cancel: cancel,
+		timer:  time.AfterFunc(d, cancel),
+	}
+}
+
+// Context returns the context that's cancelled when the deadline fires, Stop
+// is called, or the parent passed to NewDeadline is itself cancelled.
+func (d *Deadline) Context() context.Context {
+	return d.ctx
+}
+
+// Reset re-arms the deadline for a new duration, the way time.Timer.Reset
+// replaces a pending firing rather than scheduling an additional one.
+func (d *Deadline) Reset(duration time.Duration) {
+	d.timer.Reset(duration)
+}
+
+// Stop disarms the timer and cancels the context, releasing its resources.
+// Callers should defer Stop the way they'd defer a context.CancelFunc.
+func (d *Deadline) Stop() {
+	d.timer.Stop()
+	d.cancel()
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDeadlineCancelsContextWhenItFires(t *testing.T) {
+	d := NewDeadline(context.Background(), time.Millisecond)
+	defer d.Stop()
+
+	select {
+	case <-d.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the deadline to cancel its context")
+	}
+}
+
+func TestDeadlineResetPostponesExpiry(t *testing.T) {
+	d := NewDeadline(context.Background(), 10*time.Millisecond)
+	defer d.Stop()
+
+	d.Reset(time.Second)
+
+	select {
+	case <-d.Context().Done():
+		t.Fatal("expected Reset to postpone the original deadline")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDeadlineStopCancelsContext(t *testing.T) {
+	d := NewDeadline(context.Background(), time.Hour)
+	d.Stop()
+
+	select {
+	case <-d.Context().Done():
+	default:
+		t.Fatal("expected Stop to cancel the context immediately")
+	}
+}
+
+func TestWithInterruptCancelsOnParentDone(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithInterrupt(parent)
+	defer cancel()
+
+	cancelParent()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected WithInterrupt's context to be cancelled with its parent")
+	}
+}
+
+func TestWithInterruptCancelFuncStopsTheContext(t *testing.T) {
+	ctx, cancel := WithInterrupt(context.Background())
+	cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the returned CancelFunc to cancel the context")
+	}
+}
+
+// TestWithInterruptRearmsAfterSignal drives a real SIGINT through
+// watchInterrupt and checks that a later WithInterrupt call, made after that
+// signal already fired, still gets cancelled by the *next* one. A one-shot
+// interrupted channel would leave it selecting on an already-closed channel
+// forever.
+func TestWithInterruptRearmsAfterSignal(t *testing.T) {
+	first, cancelFirst := WithInterrupt(context.Background())
+	defer cancelFirst()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatalf("failed to raise SIGINT: %v", err)
+	}
+
+	select {
+	case <-first.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the first context to be cancelled by SIGINT")
+	}
+
+	second, cancelSecond := WithInterrupt(context.Background())
+	defer cancelSecond()
+
+	select {
+	case <-second.Done():
+		t.Fatal("expected the second context to survive the earlier signal")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatalf("failed to raise SIGINT: %v", err)
+	}
+
+	select {
+	case <-second.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the second context to be cancelled by the second SIGINT")
+	}
+}